@@ -3,3 +3,8 @@ module jjc
 go 1.21
 
 require github.com/gomarkdown/markdown v0.0.0-20231222211730-1d6d20845b47
+
+require (
+	golang.org/x/image v0.18.0
+	golang.org/x/text v0.16.0 // indirect
+)
@@ -2,6 +2,12 @@
 
 set -e  # Exit on any error
 
+# Pass --production to minify pages and fingerprint assets
+FLAGS=""
+if [ "$1" = "--production" ]; then
+    FLAGS="-production"
+fi
+
 echo "🚀 Starting site build..."
 
 # Build the Go site generator
@@ -13,17 +19,21 @@ if [ $? -ne 0 ]; then
     exit 1
 fi
 
+# Check content before generating anything
+echo "🔍 Linting content..."
+./scripts/builder/bin/site -cmd lint
+
 # Update homepage with latest posts
 echo "🏠 Updating homepage..."
-./scripts/builder/bin/site -cmd update-homepage
+./scripts/builder/bin/site -cmd update-homepage $FLAGS
 
 # Generate sitemap
 echo "🗺️  Updating sitemap..."
-./scripts/builder/bin/site -cmd update-sitemap
+./scripts/builder/bin/site -cmd update-sitemap $FLAGS
 
 # Update library (if implemented)
 echo "📚 Updating library..."
-./scripts/builder/bin/site -cmd update-library 2>/dev/null || echo "⚠️  Library update not implemented yet"
+./scripts/builder/bin/site -cmd update-library $FLAGS 2>/dev/null || echo "⚠️  Library update not implemented yet"
 
 echo "✅ Build completed successfully!"
-echo "📁 Generated files: index.html, sitemap.xml" 
\ No newline at end of file
+echo "📁 Generated files: index.html, sitemap.xml, posts, feeds, archive, tag and section pages (see README)" 
\ No newline at end of file
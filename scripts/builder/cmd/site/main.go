@@ -11,27 +11,78 @@ import (
 
 func main() {
 	var (
-		command = flag.String("cmd", "", "Command to run: new-post, update-homepage, update-sitemap, update-library, convert-to-markdown")
+		command = flag.String("cmd", "", "Command to run: new-post, update-homepage, update-sitemap, update-library, convert-to-markdown, import, import-bookmarks, import-books, export, lint")
 		title   = flag.String("title", "", "Post title (for new-post)")
 		desc    = flag.String("desc", "", "Post description (for new-post)")
 		tags    = flag.String("tags", "", "Post tags (comma-separated, for new-post)")
-		section = flag.String("section", "Notes", "Post section (for new-post)")
+		section = flag.String("section", "", "Post section (for new-post and import, defaults to the archetype's or Notes)")
+		kind    = flag.String("type", "note", "Content type: note, essay, book, link (for new-post)")
+		url     = flag.String("url", "", "Linked page (for new-post -type link)")
+		via     = flag.String("via", "", "Where the link was found, a name or URL (for new-post -type link)")
+		quote   = flag.String("quote", "", "Quote from the linked page (for new-post -type link)")
+		force   = flag.Bool("force", false, "Overwrite an existing file (for new-post and import)")
+		open    = flag.Bool("open", false, "Open the new file in $EDITOR (for new-post)")
+		ask     = flag.Bool("interactive", false, "Prompt for missing fields (for new-post)")
+		file    = flag.String("file", "", "File or directory to import, or file to export to (for import, import-bookmarks, import-books, export)")
+		from    = flag.String("from", "", "Export format: wordpress, ghost, hugo, jekyll (for import, detected when empty)")
+		media   = flag.String("media", "", "Directory of the old site's images (for import)")
+		format  = flag.String("format", "epub", "Export format: epub, zip (for export)")
+		covers  = flag.Bool("covers", false, "Match covers in images/books by ISBN or slug (for import-books)")
 		port    = flag.Int("port", 3000, "Port for editor server (for editor)")
+		prod    = flag.Bool("production", false, "Minify output and fingerprint assets")
+		history = flag.Bool("rebuild-history", false, "Add renames found in git log to slug-history.json (for update-homepage)")
 	)
 	flag.Parse()
 
 	generator := site.NewGenerator()
+	if err := generator.LoadConfig(); err != nil {
+		log.Fatal("Failed to load site config:", err)
+	}
+	if *prod {
+		generator.SetProduction(true)
+	}
+	if *history {
+		generator.SetRebuildHistory(true)
+	}
 
 	switch *command {
 	case "new-post":
-		if *title == "" {
+		opts := site.NewPostOptions{
+			Title:       *title,
+			Description: *desc,
+			Tags:        *tags,
+			Section:     *section,
+			Type:        *kind,
+			Force:       *force,
+			URL:         *url,
+			Via:         *via,
+			Quote:       *quote,
+		}
+		if *ask {
+			// Ask for the type unless it was given explicitly
+			typeSet := false
+			flag.Visit(func(f *flag.Flag) { typeSet = typeSet || f.Name == "type" })
+			if !typeSet {
+				opts.Type = ""
+			}
+			if err := site.PromptNewPost(&opts, os.Stdin, os.Stdout); err != nil {
+				log.Fatal("Failed to read post details:", err)
+			}
+		}
+		if opts.Title == "" {
 			fmt.Println("Error: title is required for new-post")
 			os.Exit(1)
 		}
-		if err := generator.CreateNewPost(*title, *desc, *tags, *section); err != nil {
-			log.Fatal("Failed to create new post:", err)
+		path, err := generator.CreateNewPost(opts)
+		if err != nil {
+			log.Fatal("Failed to create new post: ", err)
 		}
 		fmt.Println("New post created successfully")
+		if *open {
+			if err := site.OpenInEditor(path); err != nil {
+				log.Fatal("Failed to open editor:", err)
+			}
+		}
 
 	case "update-homepage":
 		if err := generator.UpdateHomepage(); err != nil {
@@ -57,6 +108,50 @@ func main() {
 		}
 		fmt.Println("Conversion to markdown completed")
 
+	case "import":
+		if *file == "" {
+			fmt.Println("Error: file is required for import")
+			os.Exit(1)
+		}
+		err := generator.ImportPosts(site.ImportOptions{
+			From:    *from,
+			File:    *file,
+			Media:   *media,
+			Section: *section,
+			Force:   *force,
+		})
+		if err != nil {
+			log.Fatal("Failed to import posts:", err)
+		}
+
+	case "import-bookmarks":
+		if *file == "" {
+			fmt.Println("Error: file is required for import-bookmarks")
+			os.Exit(1)
+		}
+		if err := generator.ImportBookmarks(*file); err != nil {
+			log.Fatal("Failed to import bookmarks:", err)
+		}
+
+	case "import-books":
+		if *file == "" {
+			fmt.Println("Error: file is required for import-books")
+			os.Exit(1)
+		}
+		if err := generator.ImportBooks(site.ImportBooksOptions{File: *file, MatchCovers: *covers}); err != nil {
+			log.Fatal("Failed to import books:", err)
+		}
+
+	case "export":
+		if err := generator.Export(site.ExportOptions{Format: *format, File: *file}); err != nil {
+			log.Fatal("Failed to export posts:", err)
+		}
+
+	case "lint":
+		if err := generator.Lint(); err != nil {
+			log.Fatal("Lint failed:", err)
+		}
+
 	case "editor":
 		if err := generator.StartEditor(*port); err != nil {
 			log.Fatal("Failed to start editor:", err)
@@ -64,11 +159,16 @@ func main() {
 
 	case "":
 		fmt.Println("Available commands:")
-		fmt.Println("  new-post -title \"Post Title\" -desc \"Description\" -tags \"tag1,tag2\" -section \"Notes\"")
+		fmt.Println("  new-post -title \"Post Title\" -desc \"Description\" -tags \"tag1,tag2\" -type note [-section \"Notes\"] [-force] [-open] [-interactive]")
 		fmt.Println("  update-homepage")
 		fmt.Println("  update-sitemap")
 		fmt.Println("  update-library")
 		fmt.Println("  convert-to-markdown")
+		fmt.Println("  import -file export.xml [-from wordpress|ghost|hugo|jekyll] [-media uploads/] [-section \"Notes\"] [-force]")
+		fmt.Println("  import-bookmarks -file bookmarks.html")
+		fmt.Println("  import-books -file export.csv [-covers]")
+		fmt.Println("  export [-format epub|zip] [-file site.epub]")
+		fmt.Println("  lint")
 		fmt.Println("  editor -port 3000")
 		os.Exit(1)
 
@@ -0,0 +1,350 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// apiVersion is bumped when a field is renamed or removed, and the old
+// version's files are left for whoever still reads them.
+const apiVersion = 1
+
+// apiDir is where the JSON API is written, as /api/v1/.
+var apiDir = fmt.Sprintf("/api/v%d/", apiVersion)
+
+// apiPost is a post as listed in posts.json.
+type apiPost struct {
+	Slug        string   `json:"slug"`
+	URL         string   `json:"url"`
+	JSON        string   `json:"json"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Section     string   `json:"section"`
+	Type        string   `json:"type"`
+	Tags        []string `json:"tags"`
+	Created     string   `json:"created,omitempty"`
+	Updated     string   `json:"updated,omitempty"`
+	Image       string   `json:"image"`
+	WordCount   int      `json:"wordCount"`
+	ReadingTime int      `json:"readingTime"`
+	Series      string   `json:"series,omitempty"`
+	SeriesOrder int      `json:"seriesOrder,omitempty"`
+	LinkURL     string   `json:"linkURL,omitempty"`
+	Via         string   `json:"via,omitempty"`
+	Quote       string   `json:"quote,omitempty"`
+}
+
+// apiPostDetail is a post's own JSON file.
+type apiPostDetail struct {
+	Version int `json:"version"`
+	apiPost
+	Excerpt     string     `json:"excerpt"`
+	ContentHTML string     `json:"contentHTML"`
+	Previous    *apiLink   `json:"previous,omitempty"`
+	Next        *apiLink   `json:"next,omitempty"`
+	Related     []*apiLink `json:"related"`
+	Backlinks   []*apiLink `json:"backlinks"`
+}
+
+// apiItem is a library item as listed in library.json.
+type apiItem struct {
+	ID          string   `json:"id"`
+	URL         string   `json:"url"`
+	JSON        string   `json:"json,omitempty"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Author      string   `json:"author"`
+	Year        string   `json:"year,omitempty"`
+	Tags        []string `json:"tags"`
+	Created     string   `json:"created,omitempty"`
+	Updated     string   `json:"updated,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Rating      string   `json:"rating,omitempty"`
+	Started     string   `json:"started,omitempty"`
+	Finished    string   `json:"finished,omitempty"`
+	ISBN        string   `json:"isbn,omitempty"`
+}
+
+// apiItemDetail is a library item's own JSON file. Legacy HTML pages don't
+// get one, since their content isn't markdown.
+type apiItemDetail struct {
+	Version int `json:"version"`
+	apiItem
+	Quotes      []string   `json:"quotes"`
+	ContentHTML string     `json:"contentHTML"`
+	Backlinks   []*apiLink `json:"backlinks"`
+}
+
+type apiLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type apiTag struct {
+	Name    string   `json:"name"`
+	Count   int      `json:"count"`
+	Posts   []string `json:"posts"`
+	Library []string `json:"library"`
+}
+
+// siteRelativePattern matches links and images in rendered HTML that need
+// the base URL to work outside the site.
+var siteRelativePattern = regexp.MustCompile(`\b(href|src)="/([^/"][^"]*)?"`)
+
+// generateAPI writes the JSON API: an index of endpoints, posts.json,
+// library.json, tags.json and a file per post and library item with its
+// rendered HTML. Every URL in it is absolute.
+func (g *Generator) generateAPI(posts []*Post, items []*LibraryItem) error {
+	dir := g.apiPath("")
+	written := make(map[string]bool)
+	write := func(name string, value interface{}) error {
+		content, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written[path] = true
+		return nil
+	}
+
+	// Newest first, as on the homepage
+	postList := []apiPost{}
+	for _, post := range newestFirst(posts) {
+		htmlContent, err := g.renderPostContent(post)
+		if err != nil {
+			return fmt.Errorf("failed to render post %s: %w", post.Slug, err)
+		}
+
+		summary := g.apiPostFor(post)
+		detail := apiPostDetail{
+			Version:     apiVersion,
+			apiPost:     summary,
+			Excerpt:     post.Excerpt,
+			ContentHTML: g.absoluteLinks(htmlContent),
+			Previous:    g.apiLinkTo(g.linkTo(post.Previous)),
+			Next:        g.apiLinkTo(g.linkTo(post.Next)),
+			Related:     []*apiLink{},
+			Backlinks:   []*apiLink{},
+		}
+		for _, related := range post.Related {
+			detail.Related = append(detail.Related, g.apiLinkTo(g.linkTo(related)))
+		}
+		for _, backlink := range post.Backlinks {
+			detail.Backlinks = append(detail.Backlinks, g.apiLinkTo(backlink))
+		}
+		if err := write("posts/"+post.Slug+".json", detail); err != nil {
+			return err
+		}
+		postList = append(postList, summary)
+	}
+
+	itemList := []apiItem{}
+	for _, item := range items {
+		summary, err := g.apiItemFor(item)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(item.Filename, ".md") {
+			htmlContent, err := g.renderLibraryContent(item)
+			if err != nil {
+				return fmt.Errorf("failed to render library item %s: %w", item.ID, err)
+			}
+			detail := apiItemDetail{
+				Version:     apiVersion,
+				apiItem:     summary,
+				Quotes:      append([]string{}, item.Quotes...),
+				ContentHTML: g.absoluteLinks(htmlContent),
+				Backlinks:   []*apiLink{},
+			}
+			for _, backlink := range item.Backlinks {
+				detail.Backlinks = append(detail.Backlinks, g.apiLinkTo(backlink))
+			}
+			if err := write("library/"+item.ID+".json", detail); err != nil {
+				return err
+			}
+		}
+		itemList = append(itemList, summary)
+	}
+	sort.SliceStable(itemList, func(i, j int) bool { return itemList[i].Title < itemList[j].Title })
+
+	tags := g.apiTags(postList, itemList)
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"index.json", map[string]interface{}{
+			"version":     apiVersion,
+			"title":       g.config.Title,
+			"description": g.config.Description,
+			"url":         g.absURL("/"),
+			"posts":       g.absURL(apiDir + "posts.json"),
+			"library":     g.absURL(apiDir + "library.json"),
+			"tags":        g.absURL(apiDir + "tags.json"),
+		}},
+		{"posts.json", map[string]interface{}{"version": apiVersion, "count": len(postList), "posts": postList}},
+		{"library.json", map[string]interface{}{"version": apiVersion, "count": len(itemList), "items": itemList}},
+		{"tags.json", map[string]interface{}{"version": apiVersion, "count": len(tags), "tags": tags}},
+	}
+	for _, file := range files {
+		if err := write(file.name, file.value); err != nil {
+			return err
+		}
+	}
+
+	// Drop the files of posts and items that no longer exist
+	for _, sub := range []string{"posts", "library"} {
+		existing, err := filepath.Glob(filepath.Join(dir, sub, "*.json"))
+		if err != nil {
+			return err
+		}
+		for _, path := range existing {
+			if !written[path] {
+				if err := os.Remove(path); err != nil {
+					return fmt.Errorf("failed to remove stale %s: %w", path, err)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("Generated JSON API with %d posts and %d library items\n", len(postList), len(itemList))
+	return nil
+}
+
+// apiPath is the file a JSON API file is written to.
+func (g *Generator) apiPath(name string) string {
+	return filepath.Join(g.rootDir, filepath.FromSlash(strings.TrimPrefix(apiDir, "/")), filepath.FromSlash(name))
+}
+
+func (g *Generator) apiPostFor(post *Post) apiPost {
+	// Posts without a cover use their generated preview image
+	cover := post.Cover
+	if cover == "" {
+		cover = g.previewPath(post)
+	}
+	tags := splitTags(post.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return apiPost{
+		Slug:        post.Slug,
+		URL:         g.absURL(g.postURL(post)),
+		JSON:        g.absURL(apiDir + "posts/" + post.Slug + ".json"),
+		Title:       post.Title,
+		Description: post.Description,
+		Section:     post.Section,
+		Type:        post.Type,
+		Tags:        tags,
+		Created:     isoDate(post.Created),
+		Updated:     isoDate(post.Updated),
+		Image:       g.pageImage(cover),
+		WordCount:   post.WordCount,
+		ReadingTime: post.ReadingTime,
+		Series:      post.Series,
+		SeriesOrder: post.SeriesOrder,
+		LinkURL:     post.LinkURL,
+		Via:         post.Via,
+		Quote:       post.Quote,
+	}
+}
+
+func (g *Generator) apiItemFor(item *LibraryItem) (apiItem, error) {
+	tags := splitTags(item.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	summary := apiItem{
+		ID:          item.ID,
+		URL:         g.absURL(g.libraryURL(item)),
+		Title:       item.Title,
+		Description: item.Description,
+		Author:      item.Author,
+		Year:        item.Year,
+		Tags:        tags,
+		Created:     isoDate(item.Created),
+		Updated:     isoDate(item.Updated),
+		Status:      item.Status,
+		Rating:      item.Rating,
+		Started:     isoDate(item.Started),
+		Finished:    isoDate(item.Finished),
+		ISBN:        item.ISBN,
+	}
+	if strings.HasSuffix(item.Filename, ".md") {
+		summary.JSON = g.absURL(apiDir + "library/" + item.ID + ".json")
+	}
+	cover, err := g.bookCoverFor(item)
+	if err != nil {
+		return apiItem{}, err
+	}
+	if cover.Image != "" {
+		summary.Image = g.absURL(cover.Image)
+	}
+	return summary, nil
+}
+
+func (g *Generator) apiLinkTo(link *postLink) *apiLink {
+	if link == nil {
+		return nil
+	}
+	return &apiLink{Title: link.Title, URL: g.absURL(link.URL)}
+}
+
+// apiTags lists every tag with the slugs of the posts and IDs of the library
+// items tagged with it, most used first. Tags that differ only in case are counted together.
+func (g *Generator) apiTags(posts []apiPost, items []apiItem) []*apiTag {
+	byKey := make(map[string]*apiTag)
+	var tags []*apiTag
+	tag := func(name string) *apiTag {
+		key := strings.ToLower(name)
+		if byKey[key] == nil {
+			byKey[key] = &apiTag{Name: name, Posts: []string{}, Library: []string{}}
+			tags = append(tags, byKey[key])
+		}
+		byKey[key].Count++
+		return byKey[key]
+	}
+	for _, post := range posts {
+		for _, name := range post.Tags {
+			t := tag(name)
+			t.Posts = append(t.Posts, post.Slug)
+		}
+	}
+	for _, item := range items {
+		for _, name := range item.Tags {
+			t := tag(name)
+			t.Library = append(t.Library, item.ID)
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	if tags == nil {
+		tags = []*apiTag{}
+	}
+	return tags
+}
+
+// absoluteLinks points the site-relative links and images in rendered HTML
+// at the site.
+func (g *Generator) absoluteLinks(content string) string {
+	return siteRelativePattern.ReplaceAllStringFunc(content, func(match string) string {
+		parts := siteRelativePattern.FindStringSubmatch(match)
+		return fmt.Sprintf(`%s="%s"`, parts[1], g.absURL("/"+parts[2]))
+	})
+}
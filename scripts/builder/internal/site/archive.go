@@ -0,0 +1,317 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultArchivePattern = "/archive/"
+	defaultYearsPattern   = "/:year/"
+)
+
+// The homepage's archive summary is generated between these markers.
+const (
+	homepageArchiveStart = "<!-- archive:start -->"
+	homepageArchiveEnd   = "<!-- archive:end -->"
+)
+
+// archive is every post with a parseable created date, by year and month,
+// newest first. Templates use its counts for summaries.
+type archive struct {
+	URL   string
+	Total int
+
+	// Since is the year of the oldest post
+	Since int
+
+	Years []*archiveYear
+}
+
+type archiveYear struct {
+	Year     int
+	URL      string
+	Count    int
+	Months   []*archiveMonth
+	Previous *archiveYear
+	Next     *archiveYear
+}
+
+type archiveMonth struct {
+	Name  string
+	ID    string
+	Count int
+	Rows  []noteRow
+}
+
+func (g *Generator) archiveURL() string {
+	return permalink(g.config.Permalinks.Archive, nil)
+}
+
+func (g *Generator) yearURL(year int) string {
+	return permalink(g.config.Permalinks.Years, map[string]string{"year": strconv.Itoa(year)})
+}
+
+// buildArchive groups the posts by the year and month they were created.
+// Posts without a date are left out.
+func (g *Generator) buildArchive(posts []*Post) *archive {
+	result := &archive{URL: g.archiveURL()}
+	for _, post := range newestFirst(posts) {
+		created, ok := parseDate(post.Created)
+		if !ok {
+			continue
+		}
+
+		var year *archiveYear
+		if n := len(result.Years); n > 0 && result.Years[n-1].Year == created.Year() {
+			year = result.Years[n-1]
+		} else {
+			year = &archiveYear{Year: created.Year(), URL: g.yearURL(created.Year())}
+			result.Years = append(result.Years, year)
+		}
+
+		var month *archiveMonth
+		id := created.Format("2006-01")
+		if n := len(year.Months); n > 0 && year.Months[n-1].ID == id {
+			month = year.Months[n-1]
+		} else {
+			month = &archiveMonth{Name: created.Month().String(), ID: id}
+			year.Months = append(year.Months, month)
+		}
+
+		month.Rows = append(month.Rows, g.noteRows([]*Post{post})...)
+		month.Count++
+		year.Count++
+		result.Total++
+		result.Since = created.Year()
+	}
+
+	// Years are newest first, so the previous year is the next in the list
+	for i, year := range result.Years {
+		if i > 0 {
+			year.Next = result.Years[i-1]
+		}
+		if i < len(result.Years)-1 {
+			year.Previous = result.Years[i+1]
+		}
+	}
+	return result
+}
+
+// archiveURLs are the URLs of the archive and every year page.
+func (g *Generator) archiveURLs(posts []*Post) []string {
+	result := g.buildArchive(posts)
+	if result.Total == 0 {
+		return nil
+	}
+	urls := []string{result.URL}
+	for _, year := range result.Years {
+		urls = append(urls, year.URL)
+	}
+	return urls
+}
+
+const archiveSummaryTemplate = `{{if .Total}}
+      <p class="archive-summary">{{.Total}} {{if eq .Total 1}}post{{else}}posts{{end}} since {{.Since}}. <a href="{{.URL}}">Browse the archive</a></p>{{end}}`
+
+// replaceHomepageArchive renders the archive summary into the homepage.
+func (g *Generator) replaceHomepageArchive(content string, posts []*Post) (string, error) {
+	start := strings.Index(content, homepageArchiveStart)
+	end := strings.Index(content, homepageArchiveEnd)
+	if start < 0 || end < start {
+		return content, nil
+	}
+
+	t, err := template.New("homepage-archive").Parse(archiveSummaryTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var summary strings.Builder
+	if err := t.Execute(&summary, g.buildArchive(posts)); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return content[:start+len(homepageArchiveStart)] + summary.String() + "\n      " + content[end:], nil
+}
+
+// generateArchivePages writes the archive and a page per year, and removes
+// the pages of years that no longer have posts.
+func (g *Generator) generateArchivePages(posts []*Post) error {
+	result := g.buildArchive(posts)
+	written := make(map[string]bool)
+
+	if result.Total > 0 {
+		htmlContent, err := g.generateArchiveHTML(result, nil)
+		if err != nil {
+			return fmt.Errorf("failed to generate archive: %w", err)
+		}
+		path := g.outputPath(result.URL)
+		if err := g.writePage(path, htmlContent); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		written[path] = true
+	}
+
+	for _, year := range result.Years {
+		htmlContent, err := g.generateArchiveHTML(result, year)
+		if err != nil {
+			return fmt.Errorf("failed to generate archive for %d: %w", year.Year, err)
+		}
+		path := g.outputPath(year.URL)
+		if err := g.writePage(path, htmlContent); err != nil {
+			return fmt.Errorf("failed to write archive for %d: %w", year.Year, err)
+		}
+		written[path] = true
+	}
+
+	// Only paths with a four digit year are year pages
+	stale := []string{
+		g.outputPath(result.URL),
+		g.outputPath(permalink(g.config.Permalinks.Years, map[string]string{"year": "[0-9][0-9][0-9][0-9]"})),
+	}
+	for _, pattern := range stale {
+		existing, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, path := range existing {
+			if written[path] {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove stale %s: %w", path, err)
+			}
+			// Only succeeds once the directory is empty
+			os.Remove(filepath.Dir(path))
+		}
+	}
+
+	fmt.Printf("Generated archive with %d posts and %d year pages\n", result.Total, len(result.Years))
+	return nil
+}
+
+// generateArchiveHTML renders the archive, or a single year of it.
+func (g *Generator) generateArchiveHTML(result *archive, year *archiveYear) (string, error) {
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
+  <meta name="description" content="{{.Description}}">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
+  <title>{{.Meta.Title}}</title>
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
+</head>
+<body>
+  <nav class="navbar">
+    <div class="container nav-content">
+      <a href="/" class="logo">J</a>
+      <div class="nav-links">
+        <a href="/index.html#notes">Writing</a>
+        <a href="/about.html">About</a>
+      </div>
+    </div>
+  </nav>
+  <div class="container">
+    <header class="post-heading">
+      <h1>{{.Title}}</h1>
+      <p class="post-description">{{.Description}}</p>
+    </header>
+    <main>
+      {{- if .Year}}
+      {{- range .Year.Months}}{{template "month" .}}{{end}}
+      {{- if or .Year.Previous .Year.Next}}
+      <nav class="post-nav" aria-label="More years">
+        {{- with .Year.Previous}}
+        <a href="{{.URL}}" class="post-nav-previous" rel="prev"><span>Previous</span>{{.Year}}</a>
+        {{- end}}
+        {{- with .Year.Next}}
+        <a href="{{.URL}}" class="post-nav-next" rel="next"><span>Next</span>{{.Year}}</a>
+        {{- end}}
+      </nav>
+      {{- end}}
+      <div class="back-button-container">
+        <a href="{{.Archive.URL}}" class="back-button">Back to the archive</a>
+      </div>
+      {{- else}}
+      <ul class="archive-years">
+        {{- range .Archive.Years}}
+        <li><a href="#{{.Year}}">{{.Year}}</a> <span>{{.Count}}</span></li>
+        {{- end}}
+      </ul>
+      {{- range .Archive.Years}}
+      <section class="archive-year" id="{{.Year}}">
+        <h2><a href="{{.URL}}">{{.Year}}</a> <span>{{.Count}} {{if eq .Count 1}}post{{else}}posts{{end}}</span></h2>
+        {{- range .Months}}{{template "month" .}}{{end}}
+      </section>
+      {{- end}}
+      <div class="back-button-container">
+        <a href="/" class="back-button">Back to home</a>
+      </div>
+      {{- end}}
+    </main>
+  </div>
+</body>
+</html>
+{{define "month"}}
+      <section class="archive-month" id="{{.ID}}">
+        <h3>{{.Name}} <span>{{.Count}}</span></h3>
+        <div class="notes-list">
+          {{- range .Rows}}{{template "note-row" .}}{{end}}
+        </div>
+      </section>{{end}}`
+
+	t, err := template.New("archive").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(noteRowTemplate)
+	}
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	title := "Archive"
+	description := fmt.Sprintf("%d %s since %d, by month.", result.Total, pluralize(result.Total, "post", "posts"), result.Since)
+	url := result.URL
+	if year != nil {
+		title = strconv.Itoa(year.Year)
+		description = fmt.Sprintf("%d %s from %d.", year.Count, pluralize(year.Count, "post", "posts"), year.Year)
+		url = year.URL
+	}
+	meta, err := g.listMeta(title, description, url, g.pageFeeds("", nil))
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":        meta,
+		"Title":       title,
+		"Description": description,
+		"Archive":     result,
+		"Year":        year,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,165 @@
+package site
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fingerprintedAssets are the files pages link to that get content-hashed
+// copies in production, so browsers never serve a stale version.
+var fingerprintedAssets = []string{
+	fontsCSS,
+	"styles.css",
+	"site.webmanifest",
+	"images/apple-touch-icon.png",
+	"images/favicon-32x32.png",
+	"images/favicon-16x16.png",
+}
+
+// fingerprintPattern matches the hash a fingerprinted copy adds before the extension.
+var fingerprintPattern = regexp.MustCompile(`\.[0-9a-f]{6}(\.[A-Za-z0-9]+)$`)
+
+// assetRefPattern matches local href and src attributes in hand-written pages.
+var assetRefPattern = regexp.MustCompile(`((?:href|src)=")((?:\.\./|\./|/)*)([^"?#:]+)"`)
+
+// SetProduction enables minified output and fingerprinted assets.
+func (g *Generator) SetProduction(enabled bool) {
+	g.config.Production = enabled
+}
+
+// loadAssets returns the asset manifest, mapping each asset to the path pages
+// should link to. Outside production every asset maps to itself.
+func (g *Generator) loadAssets() (map[string]string, error) {
+	if g.assets != nil {
+		return g.assets, nil
+	}
+
+	// fonts.css is generated, so it has to exist before it can be fingerprinted
+	if err := g.buildFonts(); err != nil {
+		return nil, fmt.Errorf("failed to build fonts: %w", err)
+	}
+
+	manifest := make(map[string]string)
+	for _, name := range fingerprintedAssets {
+		path := filepath.Join(g.rootDir, filepath.FromSlash(name))
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset %s: %w", name, err)
+		}
+
+		hashed := name
+		if g.config.Production {
+			if strings.HasSuffix(name, ".css") {
+				content = []byte(minifyCSS(string(content)))
+			}
+			sum := sha256.Sum256(content)
+			ext := filepath.Ext(name)
+			hashed = strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:3]) + ext
+
+			hashedPath := filepath.Join(g.rootDir, filepath.FromSlash(hashed))
+			if err := os.WriteFile(hashedPath, content, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write asset %s: %w", hashed, err)
+			}
+		}
+		manifest[name] = hashed
+
+		if err := g.removeStaleAssets(name, hashed); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.config.Production {
+		fmt.Printf("Fingerprinted %d assets\n", len(manifest))
+	}
+
+	g.assets = manifest
+	return manifest, nil
+}
+
+// removeStaleAssets deletes fingerprinted copies of an asset other than keep.
+func (g *Generator) removeStaleAssets(name, keep string) error {
+	ext := filepath.Ext(name)
+	pattern := filepath.Join(g.rootDir, filepath.FromSlash(strings.TrimSuffix(name, ext))+".*"+ext)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		rel, err := filepath.Rel(g.rootDir, match)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == keep || fingerprintPattern.ReplaceAllString(rel, "$1") != name {
+			continue
+		}
+		if err := os.Remove(match); err != nil {
+			return fmt.Errorf("failed to remove stale asset %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// assetFuncs exposes the asset manifest to page templates as {{asset "styles.css"}}.
+func (g *Generator) assetFuncs() template.FuncMap {
+	return template.FuncMap{
+		"asset": func(name string) (string, error) {
+			manifest, err := g.loadAssets()
+			if err != nil {
+				return "", err
+			}
+			if hashed, ok := manifest[name]; ok {
+				return hashed, nil
+			}
+			return name, nil
+		},
+	}
+}
+
+// rewriteAssetRefs points asset links in a hand-written page at the current
+// entries in the manifest, including links to older fingerprinted copies.
+func (g *Generator) rewriteAssetRefs(content string) (string, error) {
+	manifest, err := g.loadAssets()
+	if err != nil {
+		return "", err
+	}
+
+	return assetRefPattern.ReplaceAllStringFunc(content, func(match string) string {
+		parts := assetRefPattern.FindStringSubmatch(match)
+		name := parts[3]
+		if _, ok := manifest[name]; !ok {
+			name = fingerprintPattern.ReplaceAllString(name, "$1")
+		}
+		hashed, ok := manifest[name]
+		if !ok {
+			return match
+		}
+		return parts[1] + parts[2] + hashed + `"`
+	}), nil
+}
+
+// writePage writes a generated page, minifying it in production and adding
+// its Content-Security-Policy.
+func (g *Generator) writePage(path, content string) error {
+	if g.config.Production {
+		content = minifyHTML(content)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	page, err := filepath.Rel(g.rootDir, path)
+	if err != nil {
+		return err
+	}
+	content = g.applyCSP(filepath.ToSlash(page), content)
+	return os.WriteFile(path, []byte(content), 0644)
+}
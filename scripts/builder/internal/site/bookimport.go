@@ -0,0 +1,467 @@
+package site
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportBooksOptions controls a CSV import into library items.
+type ImportBooksOptions struct {
+	File string
+
+	// MatchCovers looks for images/books/<isbn or slug> covers
+	MatchCovers bool
+}
+
+// bookColumns lists the CSV headers each field is read from, in order of
+// preference, covering Goodreads and StoryGraph exports.
+var bookColumns = map[string][]string{
+	"title":    {"Title"},
+	"author":   {"Author", "Authors"},
+	"year":     {"Original Publication Year", "Year Published"},
+	"isbn":     {"ISBN13", "ISBN", "ISBN/UID"},
+	"rating":   {"My Rating", "Star Rating", "Rating"},
+	"finished": {"Date Read", "Last Date Read"},
+	"started":  {"Dates Read"},
+	"added":    {"Date Added"},
+	"status":   {"Exclusive Shelf", "Read Status"},
+	"review":   {"My Review", "Review"},
+	"tags":     {"Bookshelves", "Tags"},
+}
+
+// bookStatuses maps the shelves of each service to reading log statuses.
+// Books that haven't been started aren't imported.
+var bookStatuses = map[string]string{
+	"read":              statusRead,
+	"currently-reading": statusReading,
+	"did-not-finish":    statusAbandoned,
+	"abandoned":         statusAbandoned,
+	"to-read":           "",
+}
+
+// bookFieldOrder is the order fields are written to new library items.
+var bookFieldOrder = []string{"author", "year", "isbn", "cover", "status", "rating", "started", "finished"}
+
+var (
+	htmlBreakPattern    = regexp.MustCompile(`(?i)<br\s*/?>`)
+	seriesSuffixPattern = regexp.MustCompile(`\s*\([^()]*#[\d.]+\)$`)
+)
+
+// importedBook is one CSV row mapped onto library item fields.
+type importedBook struct {
+	Title  string
+	Fields map[string]string
+	Tags   string
+	Review string
+	Added  time.Time
+}
+
+// ImportBooks creates or merges library items from a Goodreads or StoryGraph
+// CSV export. Fields already set on an existing item are never replaced;
+// differences are reported as conflicts instead.
+func (g *Generator) ImportBooks(opts ImportBooksOptions) error {
+	books, skipped, err := g.readBookCSV(opts.File)
+	if err != nil {
+		return err
+	}
+
+	existing, err := g.libraryFilesByISBN()
+	if err != nil {
+		return err
+	}
+
+	created, merged, unchanged := 0, 0, 0
+	var conflicts []string
+	for _, book := range books {
+		slug := g.slugify(book.Title)
+		if slug == "" {
+			conflicts = append(conflicts, fmt.Sprintf("%q: title has no characters usable in a filename, skipped", book.Title))
+			continue
+		}
+
+		if opts.MatchCovers {
+			if cover := g.matchCover(book.Fields["isbn"], slug); cover != "" {
+				book.Fields["cover"] = cover
+			}
+		}
+
+		// Match on ISBN first, since titles differ between editions
+		path := existing[isbn13(book.Fields["isbn"])]
+		if path == "" {
+			path = filepath.Join(g.rootDir, "library", slug+".md")
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			// Legacy HTML pages would be hidden by a new markdown file
+			if _, err := os.Stat(filepath.Join(g.rootDir, "library", slug+".html")); err == nil {
+				conflicts = append(conflicts, fmt.Sprintf("library/%s.html: %q is a legacy page, migrate it to markdown to import into it", slug, book.Title))
+				continue
+			}
+			if err := g.writeImportedBook(path, book); err != nil {
+				return err
+			}
+			created++
+			continue
+		}
+
+		updated, bookConflicts, err := g.mergeImportedBook(path, book)
+		if err != nil {
+			return err
+		}
+		conflicts = append(conflicts, bookConflicts...)
+		if updated {
+			merged++
+		} else {
+			unchanged++
+		}
+	}
+
+	fmt.Printf("Read %d books from %s: %d created, %d merged, %d unchanged, %d not started skipped\n",
+		len(books), filepath.Base(opts.File), created, merged, unchanged, skipped)
+	if len(conflicts) > 0 {
+		fmt.Printf("%d conflicts (existing values were kept):\n", len(conflicts))
+		for _, conflict := range conflicts {
+			fmt.Printf("  %s\n", conflict)
+		}
+	}
+	return nil
+}
+
+// readBookCSV maps each row of an export onto library item fields. It
+// returns the number of rows skipped because the book hasn't been started.
+func (g *Generator) readBookCSV(path string) ([]importedBook, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := columns["Title"]; !ok {
+		return nil, 0, fmt.Errorf("%s has no Title column, is it a Goodreads or StoryGraph export?", filepath.Base(path))
+	}
+
+	var books []importedBook
+	skipped := 0
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		value := func(field string) string {
+			for _, name := range bookColumns[field] {
+				if i, ok := columns[name]; ok && i < len(row) {
+					v := strings.TrimSpace(row[i])
+					// Goodreads writes ISBNs as ="0441172717" so spreadsheets keep the zeros
+					if inner, ok := strings.CutPrefix(v, `="`); ok && field == "isbn" {
+						v = strings.TrimSuffix(inner, `"`)
+					}
+					if v != "" {
+						return v
+					}
+				}
+			}
+			return ""
+		}
+
+		status, known := bookStatuses[strings.ToLower(value("status"))]
+		if !known {
+			status = statusRead
+		}
+		if status == "" {
+			skipped++
+			continue
+		}
+
+		book := importedBook{
+			// Goodreads adds the series to titles, as in "Dune (Dune, #1)"
+			Title:  seriesSuffixPattern.ReplaceAllString(value("title"), ""),
+			Fields: map[string]string{"status": status},
+			Review: strings.TrimSpace(htmlBreakPattern.ReplaceAllString(value("review"), "\n")),
+		}
+		if book.Title == "" {
+			continue
+		}
+		book.Fields["author"] = value("author")
+		book.Fields["year"] = value("year")
+		if isbn := value("isbn"); validISBN(isbn) {
+			book.Fields["isbn"] = isbn
+		}
+
+		// An unrated book has a rating of 0
+		if rating, err := strconv.ParseFloat(value("rating"), 64); err == nil && rating > 0 {
+			book.Fields["rating"] = strconv.FormatFloat(rating, 'f', -1, 64)
+		}
+
+		// StoryGraph records "2024/01/02-2024/02/20" for each read
+		if start, _, found := strings.Cut(value("started"), "-"); found {
+			if date, ok := parseCSVDate(start); ok {
+				book.Fields["started"] = g.formatDate(date)
+			}
+		}
+		if date, ok := parseCSVDate(value("finished")); ok && status == statusRead {
+			book.Fields["finished"] = g.formatDate(date)
+		}
+		if date, ok := parseCSVDate(value("added")); ok {
+			book.Added = date
+		}
+
+		var tags []string
+		for _, tag := range splitTags(value("tags")) {
+			if _, isShelf := bookStatuses[strings.ToLower(tag)]; !isShelf {
+				tags = append(tags, tag)
+			}
+		}
+		book.Tags = strings.Join(tags, ", ")
+
+		books = append(books, book)
+	}
+	return books, skipped, nil
+}
+
+func parseCSVDate(value string) (time.Time, bool) {
+	for _, layout := range []string{"2006/01/02", "2006-01-02", "2006/1/2"} {
+		if date, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// libraryFilesByISBN indexes the markdown library items by ISBN-13.
+func (g *Generator) libraryFilesByISBN() (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(g.rootDir, "library", "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	byISBN := make(map[string]string)
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		metadata, _, err := g.parseMarkdownFrontmatter(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if isbn := isbn13(metadata["isbn"]); isbn != "" {
+			byISBN[isbn] = path
+		}
+	}
+	return byISBN, nil
+}
+
+// isbn13 returns the ISBN-13 form of a valid ISBN so editions written
+// either way match, or "" for anything else.
+func isbn13(isbn string) string {
+	if !validISBN(isbn) {
+		return ""
+	}
+	digits := strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(isbn))
+	if len(digits) == 13 {
+		return digits
+	}
+
+	digits = "978" + digits[:9]
+	sum := 0
+	for i, c := range digits {
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return digits + strconv.Itoa((10-sum%10)%10)
+}
+
+// matchCover finds a cover in images/books/ named after the book's ISBN or slug.
+func (g *Generator) matchCover(isbn, slug string) string {
+	var names []string
+	if isbn != "" {
+		names = append(names, strings.ReplaceAll(isbn, "-", ""), isbn13(isbn))
+	}
+	names = append(names, slug)
+	for _, name := range names {
+		for _, ext := range []string{".jpg", ".png", ".webp"} {
+			cover := "images/books/" + name + ext
+			if _, err := os.Stat(filepath.Join(g.rootDir, filepath.FromSlash(cover))); err == nil {
+				return cover
+			}
+		}
+	}
+	return ""
+}
+
+func (g *Generator) writeImportedBook(path string, book importedBook) error {
+	date := book.Added
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var content strings.Builder
+	content.WriteString("---\n")
+	fmt.Fprintf(&content, "title: %s\n", quoteYAML(book.Title))
+	content.WriteString("description: \"\"\n")
+	for _, key := range bookFieldOrder {
+		fmt.Fprintf(&content, "%s: %s\n", key, quoteYAML(book.Fields[key]))
+	}
+	fmt.Fprintf(&content, "tags: %s\n", quoteYAML(book.Tags))
+	fmt.Fprintf(&content, "created: %s\n", quoteYAML(g.formatDate(date)))
+	fmt.Fprintf(&content, "updated: %s\n", quoteYAML(g.formatDate(date)))
+	content.WriteString("type: \"book\"\n")
+	content.WriteString("---\n\n")
+	if book.Review != "" {
+		content.WriteString(book.Review + "\n")
+	} else {
+		content.WriteString("## Notes\n\n<!-- Your notes here -->\n")
+	}
+
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	fmt.Printf("Created library/%s\n", filepath.Base(path))
+	return nil
+}
+
+// mergeImportedBook fills in the fields an existing item is missing and
+// reports those that disagree with the import.
+func (g *Generator) mergeImportedBook(path string, book importedBook) (bool, []string, error) {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	metadata, body, err := g.parseMarkdownFrontmatter(string(original))
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	source := "library/" + filepath.Base(path)
+	content := string(original)
+	var conflicts []string
+
+	fields := make(map[string]string, len(book.Fields)+1)
+	for key, value := range book.Fields {
+		fields[key] = value
+	}
+	fields["tags"] = book.Tags
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		imported, current := fields[key], metadata[key]
+		switch {
+		case imported == "" || sameBookValue(key, current, imported):
+		case current == "":
+			content = setFrontmatterField(content, key, quoteYAML(imported))
+		case key == "tags":
+			// Shelves are added to the item's own tags
+			content = setFrontmatterField(content, key, quoteYAML(strings.Join(mergeTags(current, imported), ", ")))
+		default:
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s is %q here but %q in the import", source, key, current, imported))
+		}
+	}
+
+	// Reviews only go into items without notes of their own
+	if book.Review != "" && !strings.Contains(body, book.Review) {
+		if placeholderPattern.ReplaceAllString(strings.TrimSpace(body), "") == "" {
+			content = strings.TrimSuffix(content, body) + "\n" + book.Review + "\n"
+		} else {
+			conflicts = append(conflicts, fmt.Sprintf("%s: has notes, so the review from the import was not added", source))
+		}
+	}
+
+	if content == string(original) {
+		return false, conflicts, nil
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return false, nil, fmt.Errorf("failed to write %s: %w", source, err)
+	}
+	fmt.Printf("Merged %s\n", source)
+	return true, conflicts, nil
+}
+
+// placeholderPattern matches the empty notes an archetype leaves behind.
+var placeholderPattern = regexp.MustCompile(`(?s)<!--.*?-->|## Notes|\s+`)
+
+// sameBookValue compares values the way they're meant, so "4" and "4.0" or
+// two spellings of a date aren't conflicts.
+func sameBookValue(key, a, b string) bool {
+	switch key {
+	case "rating":
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errB := strconv.ParseFloat(b, 64)
+		return errA == nil && errB == nil && x == y
+	case "started", "finished":
+		x, okA := parseDate(a)
+		y, okB := parseDate(b)
+		return okA && okB && x.Equal(y)
+	case "isbn":
+		return isbn13(a) != "" && isbn13(a) == isbn13(b)
+	case "tags":
+		return len(mergeTags(a, b)) == len(splitTags(a))
+	}
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// mergeTags adds the tags in b that a doesn't have, ignoring case.
+func mergeTags(a, b string) []string {
+	tags := splitTags(a)
+	have := make(map[string]bool)
+	for _, tag := range tags {
+		have[strings.ToLower(tag)] = true
+	}
+	for _, tag := range splitTags(b) {
+		if !have[strings.ToLower(tag)] {
+			have[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// setFrontmatterField sets a field in a markdown file's frontmatter, adding
+// it at the end if it isn't there.
+func setFrontmatterField(content, key, value string) string {
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return content
+	}
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "---" {
+			lines = append(lines[:i], append([]string{key + ": " + value}, lines[i:]...)...)
+			break
+		}
+		if name, _, found := strings.Cut(line, ":"); found && strings.TrimSpace(name) == key {
+			lines[i] = key + ": " + value
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
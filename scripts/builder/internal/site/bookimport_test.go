@@ -0,0 +1,221 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadBookCSVKeepsQuotesOutsideISBNs(t *testing.T) {
+	csv := `Title,Author,ISBN,ISBN13,My Rating,Exclusive Shelf,Date Read,My Review
+"Poor Charlie's Almanack","Charles T. Munger","=""1578645018""","=""9781578645015""",5,read,2024/12/30,"He said ""invert, always invert"""
+"=Equals",Someone,"=""""","=""""",0,read,2024/01/02,
+`
+	path := filepath.Join(t.TempDir(), "goodreads.csv")
+	if err := os.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	books, _, err := NewGenerator().readBookCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 2 {
+		t.Fatalf("read %d books, want 2", len(books))
+	}
+
+	if isbn := books[0].Fields["isbn"]; isbn != "9781578645015" {
+		t.Errorf("isbn = %q, want the ISBN13 without its =\"\" wrapper", isbn)
+	}
+	if review := books[0].Review; review != `He said "invert, always invert"` {
+		t.Errorf("review = %q, want its quotes kept", review)
+	}
+	if books[1].Title != "=Equals" {
+		t.Errorf("title = %q, want \"=Equals\"", books[1].Title)
+	}
+	if isbn := books[1].Fields["isbn"]; isbn != "" {
+		t.Errorf("empty ISBN read as %q", isbn)
+	}
+}
+
+func TestMergeImportedBook(t *testing.T) {
+	g := NewGenerator()
+	g.rootDir = t.TempDir()
+	library := filepath.Join(g.rootDir, "library")
+	if err := os.MkdirAll(library, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(library, "poor-charlies-almanack.md")
+	original := `---
+title: "Poor Charlie's Almanack"
+author: "Charles T. Munger"
+isbn: "1578645018"
+rating: "4"
+finished: "2024-12-30"
+tags: "investing, Essays"
+type: "book"
+---
+
+My own notes.
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, conflicts, err := g.mergeImportedBook(path, importedBook{
+		Title: "Poor Charlie's Almanack",
+		Fields: map[string]string{
+			"author":   "Charlie Munger",
+			"year":     "2005",
+			"isbn":     "9781578645015",
+			"rating":   "4.0",
+			"finished": "December 30, 2024",
+		},
+		Tags:   "essays, biography",
+		Review: "A review from the import.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated {
+		t.Error("updated = false, want the missing year and tag merged in")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, body, err := g.parseMarkdownFrontmatter(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"author":   "Charles T. Munger",
+		"year":     "2005",
+		"isbn":     "1578645018",
+		"rating":   "4",
+		"finished": "2024-12-30",
+		"tags":     "investing, Essays, biography",
+	}
+	for key, value := range want {
+		if metadata[key] != value {
+			t.Errorf("%s = %q, want %q", key, metadata[key], value)
+		}
+	}
+	if strings.TrimSpace(body) != "My own notes." {
+		t.Errorf("body = %q, want the item's own notes kept", body)
+	}
+
+	wantConflicts := []string{
+		`library/poor-charlies-almanack.md: author is "Charles T. Munger" here but "Charlie Munger" in the import`,
+		"library/poor-charlies-almanack.md: has notes, so the review from the import was not added",
+	}
+	if !slices.Equal(conflicts, wantConflicts) {
+		t.Errorf("conflicts = %q, want %q", conflicts, wantConflicts)
+	}
+
+	// Merging the same import again changes nothing
+	updated, _, err = g.mergeImportedBook(path, importedBook{Fields: map[string]string{"year": "2005"}, Tags: "Biography"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated {
+		t.Error("second merge updated the item, want it unchanged")
+	}
+}
+
+func TestMergeImportedBookFillsPlaceholderNotes(t *testing.T) {
+	g := NewGenerator()
+	g.rootDir = t.TempDir()
+	path := filepath.Join(g.rootDir, "book.md")
+	original := "---\ntitle: \"Book\"\n---\n\n## Notes\n\n<!-- Your notes here -->\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, conflicts, err := g.mergeImportedBook(path, importedBook{Fields: map[string]string{}, Review: "Worth rereading."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("conflicts = %q, want none", conflicts)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, body, _ := g.parseMarkdownFrontmatter(string(content)); strings.TrimSpace(body) != "Worth rereading." {
+		t.Errorf("body = %q, want the review in place of the placeholder", body)
+	}
+}
+
+func TestImportBooksMatchesISBN10(t *testing.T) {
+	g := NewGenerator()
+	g.rootDir = t.TempDir()
+	library := filepath.Join(g.rootDir, "library")
+	if err := os.MkdirAll(library, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// The existing item is named differently, so only the ISBN can match it
+	existing := filepath.Join(library, "almanack.md")
+	if err := os.WriteFile(existing, []byte("---\ntitle: \"Almanack\"\nisbn: \"1-57864-501-8\"\n---\n\nNotes.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	csv := `Title,Author,ISBN13,Exclusive Shelf,Date Read
+"Poor Charlie's Almanack","Charles T. Munger","=""9781578645015""",read,2024/12/30
+`
+	path := filepath.Join(g.rootDir, "goodreads.csv")
+	if err := os.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.ImportBooks(ImportBooksOptions{File: path}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(library, "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("library has %q, want the import merged into almanack.md", files)
+	}
+	content, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, _, err := g.parseMarkdownFrontmatter(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["author"] != "Charles T. Munger" || metadata["isbn"] != "1-57864-501-8" {
+		t.Errorf("author = %q, isbn = %q, want the author added and the ISBN kept", metadata["author"], metadata["isbn"])
+	}
+}
+
+func TestSameBookValue(t *testing.T) {
+	tests := []struct {
+		key, a, b string
+		want      bool
+	}{
+		{"rating", "4", "4.0", true},
+		{"rating", "4", "5", false},
+		{"rating", "", "0", false},
+		{"finished", "2024-12-30", "December 30, 2024", true},
+		{"finished", "2024-12-30", "2024-12-31", false},
+		{"isbn", "1578645018", "9781578645015", true},
+		{"isbn", "1-57864-501-8", "978-1-57864-501-5", true},
+		{"isbn", "1578645018", "9781578645022", false},
+		{"isbn", "not an isbn", "not an isbn", false},
+		{"tags", "investing, essays", "Essays", true},
+		{"tags", "investing", "investing, essays", false},
+		{"author", "Charles T. Munger", " charles t. munger ", true},
+		{"author", "Charles T. Munger", "Charlie Munger", false},
+	}
+	for _, test := range tests {
+		if got := sameBookValue(test.key, test.a, test.b); got != test.want {
+			t.Errorf("sameBookValue(%q, %q, %q) = %v, want %v", test.key, test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bookmark is a link read from a browser or bookmarking service export.
+type bookmark struct {
+	URL   string
+	Title string
+	Note  string
+	Quote string
+	Tags  []string
+	Added time.Time
+}
+
+var (
+	netscapeLinkPattern = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a>(?:\s*<dd>([^<]*))?`)
+	htmlAttrPattern     = regexp.MustCompile(`(?i)([a-z_-]+)="([^"]*)"`)
+	htmlTagPattern      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// maxImportSlugLength keeps slugs made from long page titles readable.
+const maxImportSlugLength = 60
+
+// ImportBookmarks creates a link post for each bookmark in a Netscape
+// bookmarks HTML file or a JSON export, skipping URLs that already have one.
+func (g *Generator) ImportBookmarks(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read bookmarks: %w", err)
+	}
+
+	var bookmarks []bookmark
+	trimmed := strings.TrimSpace(string(content))
+	if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+		bookmarks, err = parseJSONBookmarks(content)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+		}
+	} else {
+		bookmarks = parseNetscapeBookmarks(trimmed)
+	}
+
+	known, err := g.existingLinkURLs()
+	if err != nil {
+		return err
+	}
+
+	// Import oldest first so the earliest bookmark keeps the plain slug
+	sort.SliceStable(bookmarks, func(i, j int) bool { return bookmarks[i].Added.Before(bookmarks[j].Added) })
+
+	imported, duplicates, unusable := 0, 0, 0
+	for _, b := range bookmarks {
+		key := normalizeLinkURL(b.URL)
+		if key == "" {
+			unusable++
+			continue
+		}
+		if known[key] {
+			duplicates++
+			continue
+		}
+
+		title := strings.TrimSpace(b.Title)
+		if title == "" {
+			title = linkHost(b.URL)
+		}
+		slug, err := g.importSlug(title)
+		if err != nil {
+			return err
+		}
+
+		_, err = g.CreateNewPost(NewPostOptions{
+			Title:       title,
+			Description: b.Note,
+			Tags:        strings.Join(b.Tags, ", "),
+			Type:        "link",
+			Slug:        slug,
+			URL:         b.URL,
+			Quote:       b.Quote,
+			Date:        b.Added,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", b.URL, err)
+		}
+		known[key] = true
+		imported++
+	}
+
+	fmt.Printf("Imported %d links from %s (%d duplicates and %d unusable URLs skipped)\n",
+		imported, filepath.Base(path), duplicates, unusable)
+	return nil
+}
+
+// existingLinkURLs reads the url of every post, including drafts, so links
+// are never imported twice.
+func (g *Generator) existingLinkURLs() (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(g.rootDir, "posts", "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		metadata, _, err := g.parseMarkdownFrontmatter(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if key := normalizeLinkURL(metadata["url"]); key != "" {
+			known[key] = true
+		}
+	}
+	return known, nil
+}
+
+// importSlug makes a slug for a title that no existing post uses.
+func (g *Generator) importSlug(title string) (string, error) {
+	base := g.slugify(title)
+	if len(base) > maxImportSlugLength {
+		base = base[:maxImportSlugLength]
+		if i := strings.LastIndex(base, "-"); i > 0 {
+			base = base[:i]
+		}
+	}
+	if base == "" {
+		base = "link"
+	}
+
+	slug := base
+	for n := 2; ; n++ {
+		_, err := os.Stat(filepath.Join(g.rootDir, "posts", slug+".md"))
+		if os.IsNotExist(err) {
+			return slug, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// normalizeLinkURL reduces a URL to the form used to spot duplicates, or
+// returns "" for anything that isn't a web page.
+func normalizeLinkURL(link string) string {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	// Tracking parameters don't change the page
+	query := parsed.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "utm_") {
+			query.Del(key)
+		}
+	}
+
+	normalized := host + strings.TrimSuffix(parsed.EscapedPath(), "/")
+	if encoded := query.Encode(); encoded != "" {
+		normalized += "?" + encoded
+	}
+	return normalized
+}
+
+// parseNetscapeBookmarks reads the bookmarks HTML format every browser exports.
+func parseNetscapeBookmarks(content string) []bookmark {
+	var bookmarks []bookmark
+	for _, match := range netscapeLinkPattern.FindAllStringSubmatch(content, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range htmlAttrPattern.FindAllStringSubmatch(match[1], -1) {
+			attrs[strings.ToLower(attr[1])] = html.UnescapeString(attr[2])
+		}
+		b := bookmark{
+			URL:   attrs["href"],
+			Title: html.UnescapeString(htmlTagPattern.ReplaceAllString(match[2], "")),
+			Note:  strings.TrimSpace(html.UnescapeString(match[3])),
+			Tags:  splitTags(attrs["tags"]),
+		}
+		if seconds, err := strconv.ParseInt(attrs["add_date"], 10, 64); err == nil {
+			b.Added = unixTime(seconds)
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks
+}
+
+// parseJSONBookmarks reads JSON exports such as Pinboard's or Firefox's.
+// Any object with a url, href or uri is a bookmark, and folders are
+// searched through their children.
+func parseJSONBookmarks(content []byte) ([]bookmark, error) {
+	var data interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+
+	var bookmarks []bookmark
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		case map[string]interface{}:
+			if b, ok := jsonBookmark(v); ok {
+				bookmarks = append(bookmarks, b)
+			}
+			for _, key := range []string{"children", "bookmarks", "items", "posts"} {
+				walk(v[key])
+			}
+		}
+	}
+	walk(data)
+	return bookmarks, nil
+}
+
+func jsonBookmark(fields map[string]interface{}) (bookmark, bool) {
+	str := func(keys ...string) string {
+		for _, key := range keys {
+			if s, ok := fields[key].(string); ok && strings.TrimSpace(s) != "" {
+				return strings.TrimSpace(s)
+			}
+		}
+		return ""
+	}
+
+	b := bookmark{URL: str("url", "href", "uri", "link")}
+	if b.URL == "" {
+		return b, false
+	}
+
+	// Pinboard calls the title "description" and the note "extended"
+	b.Title = str("title", "name")
+	if b.Title == "" {
+		b.Title = str("description")
+		b.Note = str("extended", "note", "excerpt")
+	} else {
+		b.Note = str("extended", "note", "excerpt", "description")
+	}
+	b.Quote = str("quote", "highlight")
+
+	switch tags := fields["tags"].(type) {
+	case string:
+		if strings.Contains(tags, ",") {
+			b.Tags = splitTags(tags)
+		} else {
+			b.Tags = strings.Fields(tags)
+		}
+	case []interface{}:
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok && s != "" {
+				b.Tags = append(b.Tags, s)
+			}
+		}
+	}
+
+	for _, key := range []string{"time", "created", "created_at", "dateAdded", "add_date", "added"} {
+		switch value := fields[key].(type) {
+		case string:
+			if date, err := time.Parse(time.RFC3339, value); err == nil {
+				b.Added = date
+			} else if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				b.Added = unixTime(n)
+			}
+		case float64:
+			b.Added = unixTime(int64(value))
+		}
+		if !b.Added.IsZero() {
+			break
+		}
+	}
+	return b, true
+}
+
+// unixTime reads a timestamp in seconds, milliseconds or microseconds,
+// as different browsers use each.
+func unixTime(n int64) time.Time {
+	switch {
+	case n > 1e14:
+		return time.UnixMicro(n)
+	case n > 1e11:
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
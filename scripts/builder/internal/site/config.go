@@ -0,0 +1,86 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds site-wide settings read from site.json in the root directory.
+type Config struct {
+	BaseURL      string `json:"baseURL"`
+	Title        string `json:"title"`
+	Author       string `json:"author"`
+	Description  string `json:"description"`
+	DefaultImage string `json:"defaultImage"`
+	Twitter      string `json:"twitter"`
+	Locale       string `json:"locale"`
+	FontDisplay  string `json:"fontDisplay"`
+	Production   bool   `json:"production"`
+
+	Markdown   MarkdownConfig   `json:"markdown"`
+	Permalinks PermalinkConfig  `json:"permalinks"`
+	Pagination PaginationConfig `json:"pagination"`
+	Security   SecurityConfig   `json:"security"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		BaseURL:      "https://jordanjoecooper.dev",
+		Title:        "Jordan Joe Cooper",
+		Author:       "Jordan Joe Cooper",
+		Description:  "Making things on the internet.",
+		DefaultImage: "images/apple-touch-icon.png",
+		Locale:       "en_GB",
+		FontDisplay:  "swap",
+		Markdown: MarkdownConfig{
+			Footnotes:       true,
+			Callouts:        true,
+			DefinitionLists: true,
+			Math:            true,
+			Diagrams:        true,
+		},
+		Permalinks: PermalinkConfig{
+			Posts:    legacyPostPattern,
+			Library:  legacyLibraryPattern,
+			Series:   legacySeriesPattern,
+			Links:    legacyLinksPattern,
+			Reading:  legacyReadingPattern,
+			Tags:     defaultTagsPattern,
+			Sections: defaultSectionsPattern,
+			Archive:  defaultArchivePattern,
+			Years:    defaultYearsPattern,
+		},
+		Pagination: PaginationConfig{
+			PageSize: defaultPageSize,
+			Path:     defaultPaginationPath,
+		},
+		Security: SecurityConfig{
+			CSP:               true,
+			HSTSMaxAge:        31536000,
+			ReferrerPolicy:    "strict-origin-when-cross-origin",
+			PermissionsPolicy: "camera=(), microphone=(), geolocation=(), browsing-topics=()",
+		},
+	}
+}
+
+// LoadConfig reads site.json, keeping the defaults for any missing fields.
+func (g *Generator) LoadConfig() error {
+	configPath := filepath.Join(g.rootDir, "site.json")
+	content, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read site config: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &g.config); err != nil {
+		return fmt.Errorf("failed to parse site config %s: %w", configPath, err)
+	}
+	g.config.BaseURL = strings.TrimSuffix(g.config.BaseURL, "/")
+
+	return nil
+}
@@ -0,0 +1,362 @@
+package site
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"html/template"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	coversDir = "images/books/covers"
+
+	// Covers are cropped to 2:3, the shape of most paperbacks
+	coverAspectWidth  = 2
+	coverAspectHeight = 3
+
+	// coverVersion is part of each cover's hash; bump it when the processing
+	// changes so covers are regenerated.
+	coverVersion = "1"
+	coverQuality = 85
+)
+
+// The homepage library grid is generated between these markers.
+const (
+	homepageLibraryStart = "<!-- library:start -->"
+	homepageLibraryEnd   = "<!-- library:end -->"
+
+	// homepageBooks is how many books the homepage shows
+	homepageBooks = 8
+)
+
+// coverWidths are the sizes each cover is resized to for srcset.
+var coverWidths = []int{200, 400, 600}
+
+// coverExtensions are the image types a cover in images/books can have.
+var coverExtensions = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// placeholderColors are the backgrounds of generated covers, picked by title.
+var placeholderColors = []string{"#1f3a5f", "#5f1f2e", "#2e5f3a", "#5f4b1f", "#3d2e5f", "#1f5a5f", "#4a4a4a"}
+
+// bookCover is a library item's cover, ready to be used in an <img>.
+type bookCover struct {
+	Src    string
+	Srcset string
+	Width  int
+	Height int
+
+	// Image is the largest JPEG, used for social previews; placeholder
+	// covers don't have one
+	Image string
+
+	// files are the generated files in coversDir
+	files []string
+}
+
+// coverSource finds the image a library item's cover is made from: its cover
+// field if that's a local image, otherwise images/books/<id> in any format.
+func (g *Generator) coverSource(item *LibraryItem) string {
+	if item.Cover != "" && !strings.HasPrefix(item.Cover, "http") && !strings.HasPrefix(item.Cover, "/"+coversDir) {
+		return strings.TrimPrefix(item.Cover, "/")
+	}
+	for _, ext := range coverExtensions {
+		cover := "images/books/" + item.ID + ext
+		if _, err := os.Stat(filepath.Join(g.rootDir, filepath.FromSlash(cover))); err == nil {
+			return cover
+		}
+	}
+	return ""
+}
+
+// bookCoverFor returns an item's cover, resizing its image or drawing a
+// placeholder the first time it's asked for.
+func (g *Generator) bookCoverFor(item *LibraryItem) (*bookCover, error) {
+	if cover, ok := g.covers[item.ID]; ok {
+		return cover, nil
+	}
+
+	var cover *bookCover
+	var err error
+	switch source := g.coverSource(item); {
+	case strings.HasPrefix(item.Cover, "http"):
+		// Remote covers can't be processed, so they're used as they are
+		cover = &bookCover{Src: item.Cover, Image: item.Cover}
+	case source != "":
+		cover, err = g.resizeCover(item, source)
+	default:
+		cover, err = g.placeholderCover(item)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to make cover for library item %s: %w", item.ID, err)
+	}
+
+	if g.covers == nil {
+		g.covers = make(map[string]*bookCover)
+	}
+	g.covers[item.ID] = cover
+	return cover, nil
+}
+
+// prepareCovers makes the covers of every item, so pages can use them.
+func (g *Generator) prepareCovers(items []*LibraryItem) error {
+	for _, item := range items {
+		if _, err := g.bookCoverFor(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coverName is the file name of a generated cover. The hash changes with the
+// input, so browsers never keep a stale cover.
+func coverName(id, suffix string, inputs ...[]byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(coverVersion))
+	for _, input := range inputs {
+		sum.Write(input)
+		sum.Write([]byte{0})
+	}
+	return id + "." + hex.EncodeToString(sum.Sum(nil)[:3]) + suffix
+}
+
+// resizeCover crops a cover image to 2:3 around its centre and writes a JPEG
+// at each of coverWidths no larger than the crop.
+func (g *Generator) resizeCover(item *LibraryItem, source string) (*bookCover, error) {
+	data, err := os.ReadFile(filepath.Join(g.rootDir, filepath.FromSlash(source)))
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	cropWidth := coverCrop(image.Rect(0, 0, config.Width, config.Height)).Dx()
+
+	dir := filepath.Join(g.rootDir, filepath.FromSlash(coversDir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", coversDir, err)
+	}
+
+	cover := &bookCover{
+		Width:  coverWidths[0],
+		Height: coverWidths[0] * coverAspectHeight / coverAspectWidth,
+	}
+	var srcset []string
+	var img image.Image
+	for i, width := range coverWidths {
+		// Small images aren't scaled up, except to the smallest size
+		if i > 0 && width > cropWidth {
+			break
+		}
+		name := coverName(item.ID, fmt.Sprintf("-%d.jpg", width), data)
+		url := "/" + coversDir + "/" + name
+
+		// Covers are only decoded when a size hasn't been made yet
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if img == nil {
+				if img, _, err = image.Decode(bytes.NewReader(data)); err != nil {
+					return nil, fmt.Errorf("failed to decode %s: %w", source, err)
+				}
+			}
+			if err := writeCoverJPEG(path, img, width); err != nil {
+				return nil, err
+			}
+		}
+
+		if i == 0 {
+			cover.Src = url
+		}
+		cover.Image = url
+		cover.files = append(cover.files, name)
+		srcset = append(srcset, fmt.Sprintf("%s %dw", url, width))
+	}
+	cover.Srcset = strings.Join(srcset, ", ")
+	return cover, nil
+}
+
+func writeCoverJPEG(path string, img image.Image, width int) error {
+	height := width * coverAspectHeight / coverAspectWidth
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// JPEG has no transparency, so transparent covers go on white
+	draw.Draw(resized, resized.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, coverCrop(img.Bounds()), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: coverQuality}); err != nil {
+		return fmt.Errorf("failed to encode cover: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write cover: %w", err)
+	}
+	return nil
+}
+
+// coverCrop returns the largest 2:3 rectangle centred in bounds.
+func coverCrop(bounds image.Rectangle) image.Rectangle {
+	width, height := bounds.Dx(), bounds.Dy()
+	if width*coverAspectHeight > height*coverAspectWidth {
+		cropWidth := height * coverAspectWidth / coverAspectHeight
+		x := bounds.Min.X + (width-cropWidth)/2
+		return image.Rect(x, bounds.Min.Y, x+cropWidth, bounds.Max.Y)
+	}
+	cropHeight := width * coverAspectHeight / coverAspectWidth
+	y := bounds.Min.Y + (height-cropHeight)/2
+	return image.Rect(bounds.Min.X, y, bounds.Max.X, y+cropHeight)
+}
+
+// placeholderCover draws a typographic SVG cover from an item's title and author.
+func (g *Generator) placeholderCover(item *LibraryItem) (*bookCover, error) {
+	svg := placeholderSVG(item.Title, item.Author)
+	name := coverName(item.ID, ".svg", []byte(svg))
+	dir := filepath.Join(g.rootDir, filepath.FromSlash(coversDir))
+	path := filepath.Join(dir, name)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", coversDir, err)
+		}
+		if err := os.WriteFile(path, []byte(svg), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write placeholder cover: %w", err)
+		}
+	}
+
+	return &bookCover{
+		Src:    "/" + coversDir + "/" + name,
+		Width:  coverWidths[0],
+		Height: coverWidths[0] * coverAspectHeight / coverAspectWidth,
+		files:  []string{name},
+	}, nil
+}
+
+func placeholderSVG(title, author string) string {
+	sum := sha256.Sum256([]byte(title))
+	background := placeholderColors[int(sum[0])%len(placeholderColors)]
+
+	// The viewBox is 200x300; titles get smaller as they get longer
+	size, maxChars := 24, 13
+	if len(title) > 40 {
+		size, maxChars = 18, 17
+	}
+	lines := wrapWords(title, maxChars, 6)
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 300" width="200" height="300" role="img" aria-label="%s">`,
+		html.EscapeString(title))
+	fmt.Fprintf(&svg, `<rect width="200" height="300" fill="%s"/>`, background)
+	svg.WriteString(`<rect x="12" y="12" width="176" height="276" fill="none" stroke="#ffffff" stroke-opacity="0.35"/>`)
+	fmt.Fprintf(&svg, `<text x="100" y="%d" fill="#ffffff" font-family="Georgia, 'Times New Roman', serif" font-size="%d" text-anchor="middle">`,
+		150-len(lines)*(size+4)/2+size/2, size)
+	for i, line := range lines {
+		dy := 0
+		if i > 0 {
+			dy = size + 4
+		}
+		fmt.Fprintf(&svg, `<tspan x="100" dy="%d">%s</tspan>`, dy, html.EscapeString(line))
+	}
+	svg.WriteString(`</text>`)
+	if author != "" {
+		fmt.Fprintf(&svg, `<text x="100" y="262" fill="#ffffff" fill-opacity="0.8" font-family="Helvetica, Arial, sans-serif" font-size="12" text-anchor="middle" letter-spacing="1">%s</text>`,
+			html.EscapeString(strings.ToUpper(author)))
+	}
+	svg.WriteString("</svg>\n")
+	return svg.String()
+}
+
+// wrapWords splits text into lines of at most maxChars characters where it
+// can, ending with an ellipsis if it needs more than maxLines.
+func wrapWords(text string, maxChars, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > maxChars {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] += "…"
+	}
+	return lines
+}
+
+// replaceHomepageLibrary fills the library grid of the homepage, between
+// the library markers, with the most recent books.
+func (g *Generator) replaceHomepageLibrary(content string, items []*LibraryItem) (string, error) {
+	start := strings.Index(content, homepageLibraryStart)
+	end := strings.Index(content, homepageLibraryEnd)
+	if start < 0 || end < start {
+		return content, nil
+	}
+
+	if err := g.prepareCovers(items); err != nil {
+		return "", err
+	}
+	reading, years, _ := g.groupBooks(items)
+	entries := reading
+	for _, year := range years {
+		entries = append(entries, year.Books...)
+	}
+	if len(entries) > homepageBooks {
+		entries = entries[:homepageBooks]
+	}
+
+	t, err := template.New("homepage-library").Parse(`{{range .}}{{template "book" .}}{{end}}`)
+	if err == nil {
+		_, err = t.Parse(bookTemplate)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var grid strings.Builder
+	if err := t.Execute(&grid, entries); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return content[:start+len(homepageLibraryStart)] + grid.String() + "\n        " + content[end:], nil
+}
+
+// removeStaleCovers deletes generated covers no library item uses any more.
+func (g *Generator) removeStaleCovers() error {
+	used := make(map[string]bool)
+	for _, cover := range g.covers {
+		for _, name := range cover.files {
+			used[name] = true
+		}
+	}
+
+	existing, err := filepath.Glob(filepath.Join(g.rootDir, filepath.FromSlash(coversDir), "*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range existing {
+		if !used[filepath.Base(path)] {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove old cover: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package site
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SecurityConfig controls the Content-Security-Policy and the security
+// headers written to _headers.
+type SecurityConfig struct {
+	CSP               bool                `json:"csp"`
+	Sources           map[string][]string `json:"sources"`
+	HSTSMaxAge        int                 `json:"hstsMaxAge"`
+	ReferrerPolicy    string              `json:"referrerPolicy"`
+	PermissionsPolicy string              `json:"permissionsPolicy"`
+}
+
+var (
+	inlineScriptPattern = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	inlineStylePattern  = regexp.MustCompile(`(?is)<style\b[^>]*>(.*?)</style>`)
+	styleAttrPattern    = regexp.MustCompile(`(?i)<([a-z][a-z0-9-]*)\b[^>]*\sstyle="([^"]*)"`)
+	handlerAttrPattern  = regexp.MustCompile(`(?i)<([a-z][a-z0-9-]*)\b[^>]*\s(on[a-z]+)=`)
+	externalSrcPattern  = regexp.MustCompile(`(?i)<(img|script|iframe|audio|video|source)\b[^>]*\ssrc="(https?://[^/"]+)`)
+	scriptTypePattern   = regexp.MustCompile(`(?i)\stype="([^"]*)"`)
+	cspMetaPattern      = regexp.MustCompile(`\s*<meta http-equiv="Content-Security-Policy"[^>]*>`)
+	charsetMetaPattern  = regexp.MustCompile(`(?i)<meta charset="[^"]*">`)
+)
+
+// externalSrcDirectives maps elements that load external resources to the
+// CSP directive that governs them.
+var externalSrcDirectives = map[string]string{
+	"img":    "img-src",
+	"script": "script-src",
+	"iframe": "frame-src",
+	"audio":  "media-src",
+	"video":  "media-src",
+	"source": "media-src",
+}
+
+// applyCSP adds a Content-Security-Policy <meta> to a page that allows its
+// own inline scripts and styles by hash, replacing any policy from an
+// earlier build. It warns about inline code the policy would block.
+func (g *Generator) applyCSP(page, content string) string {
+	if !g.config.Security.CSP {
+		return content
+	}
+	content = cspMetaPattern.ReplaceAllString(content, "")
+
+	var scriptHashes, styleHashes []string
+	for _, match := range inlineScriptPattern.FindAllStringSubmatch(content, -1) {
+		attrs, body := match[1], match[2]
+		if strings.Contains(strings.ToLower(attrs), " src=") || !isJavaScriptType(attrs) {
+			continue
+		}
+		scriptHashes = append(scriptHashes, cspHash(body))
+	}
+	for _, match := range inlineStylePattern.FindAllStringSubmatch(content, -1) {
+		styleHashes = append(styleHashes, cspHash(match[1]))
+	}
+
+	for _, match := range styleAttrPattern.FindAllStringSubmatch(content, -1) {
+		fmt.Printf("Warning: %s: inline style on <%s> is blocked by the Content-Security-Policy, move %q to styles.css\n",
+			page, match[1], match[2])
+	}
+	for _, match := range handlerAttrPattern.FindAllStringSubmatch(content, -1) {
+		fmt.Printf("Warning: %s: %s handler on <%s> is blocked by the Content-Security-Policy\n", page, match[2], match[1])
+	}
+	for _, match := range externalSrcPattern.FindAllStringSubmatch(content, -1) {
+		directive := externalSrcDirectives[strings.ToLower(match[1])]
+		if !containsString(g.config.Security.Sources[directive], match[2]) {
+			fmt.Printf("Warning: %s: <%s> from %s is blocked by the Content-Security-Policy, add it to security.sources[%q] in site.json\n",
+				page, match[1], match[2], directive)
+		}
+	}
+
+	policy := g.pagePolicy(scriptHashes, styleHashes)
+	meta := fmt.Sprintf(`<meta http-equiv="Content-Security-Policy" content="%s">`, policy)
+
+	if !g.config.Production {
+		meta = "\n  " + meta
+	}
+
+	// The policy has to come before anything it applies to
+	if loc := charsetMetaPattern.FindStringIndex(content); loc != nil {
+		return content[:loc[1]] + meta + content[loc[1]:]
+	}
+	if i := strings.Index(content, "<head>"); i >= 0 {
+		return content[:i+len("<head>")] + meta + content[i+len("<head>"):]
+	}
+	return content
+}
+
+// pagePolicy builds the per-page policy. Directives that <meta> can't carry,
+// such as frame-ancestors, are sent in _headers instead.
+func (g *Generator) pagePolicy(scriptHashes, styleHashes []string) string {
+	directives := [][]string{
+		{"default-src", "'self'"},
+		{"script-src", "'self'"},
+		{"style-src", "'self'"},
+		{"img-src", "'self'", "data:"},
+		{"font-src", "'self'"},
+		{"media-src", "'self'"},
+		{"frame-src", "'none'"},
+		{"object-src", "'none'"},
+		{"base-uri", "'self'"},
+		{"form-action", "'self'"},
+	}
+
+	var policy []string
+	for _, directive := range directives {
+		name := directive[0]
+		sources := directive[1:]
+		extra := g.config.Security.Sources[name]
+		if len(extra) > 0 && sources[0] == "'none'" {
+			sources = nil
+		}
+		sources = append(sources, extra...)
+		switch name {
+		case "script-src":
+			sources = append(sources, scriptHashes...)
+		case "style-src":
+			sources = append(sources, styleHashes...)
+		}
+		policy = append(policy, name+" "+strings.Join(uniqueStrings(sources), " "))
+	}
+	return strings.Join(policy, "; ")
+}
+
+// WriteHeaders writes _headers in the Netlify and Cloudflare Pages format
+// with the site's security headers.
+func (g *Generator) WriteHeaders() error {
+	var headers strings.Builder
+	headers.WriteString("# Generated by the site builder. Do not edit.\n/*\n")
+
+	if g.config.Security.CSP {
+		headers.WriteString("  Content-Security-Policy: frame-ancestors 'none'; object-src 'none'; base-uri 'self'; form-action 'self'")
+		if strings.HasPrefix(g.config.BaseURL, "https://") {
+			headers.WriteString("; upgrade-insecure-requests")
+		}
+		headers.WriteString("\n")
+	}
+	if strings.HasPrefix(g.config.BaseURL, "https://") && g.config.Security.HSTSMaxAge > 0 {
+		fmt.Fprintf(&headers, "  Strict-Transport-Security: max-age=%d; includeSubDomains\n", g.config.Security.HSTSMaxAge)
+	}
+	if g.config.Security.ReferrerPolicy != "" {
+		fmt.Fprintf(&headers, "  Referrer-Policy: %s\n", g.config.Security.ReferrerPolicy)
+	}
+	if g.config.Security.PermissionsPolicy != "" {
+		fmt.Fprintf(&headers, "  Permissions-Policy: %s\n", g.config.Security.PermissionsPolicy)
+	}
+	headers.WriteString("  X-Content-Type-Options: nosniff\n")
+
+	// Font subsets are always fingerprinted, so they never change under a URL
+	fmt.Fprintf(&headers, "\n/%s/*\n  Cache-Control: public, max-age=31536000, immutable\n", fontsSubsetDir)
+
+	headersPath := filepath.Join(g.rootDir, "_headers")
+	if err := os.WriteFile(headersPath, []byte(headers.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write _headers: %w", err)
+	}
+
+	fmt.Println("Headers written to _headers")
+	return nil
+}
+
+func isJavaScriptType(attrs string) bool {
+	match := scriptTypePattern.FindStringSubmatch(attrs)
+	if match == nil {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(match[1])) {
+	case "", "text/javascript", "application/javascript", "module":
+		return true
+	}
+	return false
+}
+
+func cspHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
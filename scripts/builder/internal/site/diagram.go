@@ -0,0 +1,828 @@
+package site
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+)
+
+// diagramVersion is part of the cache key; bump it when rendering changes.
+const diagramVersion = "1"
+
+const (
+	diagramFontSize   = 14
+	diagramCharWidth  = 7.5
+	diagramNodeHeight = 40
+	diagramNodePad    = 24
+	diagramRankGap    = 70
+	diagramNodeGap    = 40
+	diagramMargin     = 20
+)
+
+// diagramNode is a box in a flowchart or graph.
+type diagramNode struct {
+	ID    string
+	Label string
+	Shape string // rect, round, diamond, circle
+	Rank  int
+	Order float64
+	X, Y  float64
+	W, H  float64
+}
+
+type diagramEdge struct {
+	From, To string
+	Label    string
+	Style    string // solid, dashed, thick
+	Directed bool
+}
+
+type diagramGraph struct {
+	Horizontal bool
+	Nodes      []*diagramNode
+	Edges      []*diagramEdge
+	byID       map[string]*diagramNode
+}
+
+func newDiagramGraph() *diagramGraph {
+	return &diagramGraph{byID: make(map[string]*diagramNode)}
+}
+
+// node returns the node with the given ID, creating it if needed. A non-empty
+// label or shape overrides what an earlier mention set.
+func (d *diagramGraph) node(id, label, shape string) *diagramNode {
+	n, ok := d.byID[id]
+	if !ok {
+		n = &diagramNode{ID: id, Label: id, Shape: "rect"}
+		d.byID[id] = n
+		d.Nodes = append(d.Nodes, n)
+	}
+	if label != "" {
+		n.Label = label
+	}
+	if shape != "" {
+		n.Shape = shape
+	}
+	return n
+}
+
+// diagramHook renders ```mermaid and ```dot code blocks as inline SVG.
+func (g *Generator) diagramHook(errs *[]error) html.RenderNodeFunc {
+	return func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+		block, ok := node.(*ast.CodeBlock)
+		if !ok {
+			return ast.GoToNext, false
+		}
+		kind := strings.ToLower(strings.TrimSpace(string(block.Info)))
+		if kind != "mermaid" && kind != "dot" {
+			return ast.GoToNext, false
+		}
+
+		source := string(block.Literal)
+		svg, err := g.cachedDiagram(kind, source)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s diagram: %w", kind, err))
+			return ast.GoToNext, true
+		}
+
+		fmt.Fprintf(w, "<figure class=\"diagram\">\n%s\n<details>\n<summary>Diagram source</summary>\n<pre><code class=\"language-%s\">%s</code></pre>\n</details>\n</figure>\n",
+			svg, kind, template.HTMLEscapeString(source))
+		return ast.GoToNext, true
+	}
+}
+
+// cachedDiagram renders a diagram, reusing the SVG cached for an identical block.
+func (g *Generator) cachedDiagram(kind, source string) (string, error) {
+	sum := sha256.Sum256([]byte(diagramVersion + "\x00" + kind + "\x00" + source))
+	cacheDir := filepath.Join(g.rootDir, ".cache", "diagrams")
+	cachePath := filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".svg")
+
+	if cached, err := os.ReadFile(cachePath); err == nil {
+		return string(cached), nil
+	}
+
+	svg, err := renderDiagram(kind, source, "diagram-"+hex.EncodeToString(sum[:4]))
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create diagram cache: %w", err)
+	}
+	if err := os.WriteFile(cachePath, []byte(svg), 0644); err != nil {
+		return "", fmt.Errorf("failed to write diagram cache: %w", err)
+	}
+	return svg, nil
+}
+
+// renderDiagram renders a diagram to SVG. The id keeps marker definitions
+// unique when several diagrams share a page.
+func renderDiagram(kind, source, id string) (string, error) {
+	if kind == "dot" {
+		graph, err := parseDot(source)
+		if err != nil {
+			return "", err
+		}
+		return renderGraphSVG(graph, id), nil
+	}
+
+	header, _, _ := strings.Cut(strings.TrimSpace(source), "\n")
+	fields := strings.Fields(header)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty diagram")
+	}
+
+	switch fields[0] {
+	case "graph", "flowchart":
+		graph, err := parseFlowchart(source)
+		if err != nil {
+			return "", err
+		}
+		return renderGraphSVG(graph, id), nil
+	case "sequenceDiagram":
+		seq, err := parseSequence(source)
+		if err != nil {
+			return "", err
+		}
+		return renderSequenceSVG(seq, id), nil
+	}
+	return "", fmt.Errorf("unsupported diagram type %q (supported: graph, flowchart, sequenceDiagram)", fields[0])
+}
+
+var (
+	// flowNodePattern matches a node ID with an optional shaped label.
+	flowNodePattern = regexp.MustCompile(`^([A-Za-z0-9_]+)\s*(?:\(\((.*?)\)\)|\[(.*?)\]|\((.*?)\)|\{(.*?)\})?`)
+	// flowEdgePattern matches an edge operator with an optional label.
+	flowEdgePattern = regexp.MustCompile(`^(?:--\s*([^->|]+?)\s*-->|==\s*([^=>|]+?)\s*==>|-->|---|-\.->|-\.-|==>)\s*(?:\|([^|]*)\|)?`)
+)
+
+// parseFlowchart parses the Mermaid flowchart subset: graph/flowchart
+// TD|TB|BT|LR|RL headers, chained edges and rect/round/diamond/circle nodes.
+// Statements are separated by newlines or semicolons.
+func parseFlowchart(source string) (*diagramGraph, error) {
+	graph := newDiagramGraph()
+	lines := strings.Split(strings.TrimSpace(source), "\n")
+
+	first := splitStatements(lines[0])
+	header := strings.Fields(first[0])
+	if len(header) > 1 {
+		switch strings.ToUpper(header[1]) {
+		case "LR", "RL":
+			graph.Horizontal = true
+		case "TD", "TB", "BT":
+		default:
+			return nil, fmt.Errorf("line 1: unsupported direction %q", header[1])
+		}
+	}
+
+	for i, line := range lines {
+		statements := splitStatements(line)
+		if i == 0 {
+			statements = statements[1:]
+		}
+		for _, statement := range statements {
+			if strings.HasPrefix(statement, "%%") {
+				break
+			}
+			if err := parseFlowStatement(graph, statement); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+	}
+
+	if len(graph.Nodes) == 0 {
+		return nil, fmt.Errorf("diagram has no nodes")
+	}
+	return graph, nil
+}
+
+// parseFlowStatement adds a node, or a chain of nodes and edges, to a graph.
+func parseFlowStatement(graph *diagramGraph, statement string) error {
+	var previous *diagramNode
+	for statement != "" {
+		match := flowNodePattern.FindStringSubmatch(statement)
+		if match == nil {
+			return fmt.Errorf("expected a node at %q", statement)
+		}
+		label, shape := "", ""
+		switch {
+		case match[2] != "":
+			label, shape = match[2], "circle"
+		case match[3] != "":
+			label, shape = match[3], "rect"
+		case match[4] != "":
+			label, shape = match[4], "round"
+		case match[5] != "":
+			label, shape = match[5], "diamond"
+		}
+		current := graph.node(match[1], strings.Trim(label, `"`), shape)
+		statement = strings.TrimSpace(statement[len(match[0]):])
+
+		if previous != nil {
+			graph.Edges[len(graph.Edges)-1].To = current.ID
+		}
+		if statement == "" {
+			break
+		}
+
+		edge := flowEdgePattern.FindStringSubmatch(statement)
+		if edge == nil {
+			return fmt.Errorf("unsupported syntax %q", statement)
+		}
+		style := "solid"
+		switch {
+		case strings.HasPrefix(edge[0], "-."):
+			style = "dashed"
+		case strings.HasPrefix(edge[0], "=="):
+			style = "thick"
+		}
+		graph.Edges = append(graph.Edges, &diagramEdge{
+			From:     current.ID,
+			Label:    strings.TrimSpace(edge[1] + edge[2] + edge[3]),
+			Style:    style,
+			Directed: strings.Contains(edge[0], ">"),
+		})
+		previous = current
+		statement = strings.TrimSpace(statement[len(edge[0]):])
+		if statement == "" {
+			return fmt.Errorf("edge has no target")
+		}
+	}
+	return nil
+}
+
+// splitStatements splits diagram source on semicolons and newlines, except
+// within double quotes and brackets, dropping empty statements. It always
+// returns at least one statement.
+func splitStatements(source string) []string {
+	var statements []string
+	start, depth, quoted := 0, 0, false
+	add := func(end int) {
+		if statement := strings.TrimSpace(source[start:end]); statement != "" {
+			statements = append(statements, statement)
+		}
+		start = end + 1
+	}
+	for i := 0; i < len(source); i++ {
+		switch c := source[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			depth = max(depth-1, 0)
+		case depth == 0 && (c == ';' || c == '\n'):
+			add(i)
+		}
+	}
+	add(len(source))
+	if len(statements) == 0 {
+		return []string{""}
+	}
+	return statements
+}
+
+var (
+	dotHeaderPattern    = regexp.MustCompile(`^\s*(strict\s+)?(digraph|graph)\s*("[^"]*"|[A-Za-z0-9_]*)\s*\{`)
+	dotAttributePattern = regexp.MustCompile(`([A-Za-z_]+)\s*=\s*("(?:[^"\\]|\\.)*"|[^,\s\]]+)`)
+	dotIDPattern        = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|[A-Za-z0-9_.]+)$`)
+)
+
+// parseDot parses a simple subset of Graphviz DOT: node and edge statements
+// with label/shape/style attributes and a rankdir graph attribute.
+func parseDot(source string) (*diagramGraph, error) {
+	header := dotHeaderPattern.FindStringSubmatchIndex(source)
+	if header == nil {
+		return nil, fmt.Errorf("expected digraph or graph header")
+	}
+	directed := source[header[4]:header[5]] == "digraph"
+	body := source[header[1]:]
+	end := strings.LastIndex(body, "}")
+	if end < 0 {
+		return nil, fmt.Errorf("missing closing }")
+	}
+	body = body[:end]
+
+	graph := newDiagramGraph()
+	operator := "--"
+	if directed {
+		operator = "->"
+	}
+
+	for _, statement := range splitStatements(body) {
+		if strings.HasPrefix(statement, "//") || strings.HasPrefix(statement, "#") {
+			continue
+		}
+
+		attributes := map[string]string{}
+		if open := strings.Index(statement, "["); open >= 0 {
+			close := strings.LastIndex(statement, "]")
+			if close < open {
+				return nil, fmt.Errorf("unterminated attribute list in %q", statement)
+			}
+			for _, attr := range dotAttributePattern.FindAllStringSubmatch(statement[open+1:close], -1) {
+				attributes[attr[1]] = unquoteDot(attr[2])
+			}
+			statement = strings.TrimSpace(statement[:open])
+		}
+
+		if key, value, ok := strings.Cut(statement, "="); ok && !strings.Contains(statement, operator) {
+			if strings.TrimSpace(key) == "rankdir" {
+				graph.Horizontal = strings.Trim(strings.TrimSpace(value), `"`) == "LR"
+			}
+			continue
+		}
+
+		if strings.HasPrefix(statement, "node") || strings.HasPrefix(statement, "edge") || strings.HasPrefix(statement, "graph") {
+			if rankdir, ok := attributes["rankdir"]; ok {
+				graph.Horizontal = rankdir == "LR"
+			}
+			continue
+		}
+		if strings.HasPrefix(statement, "subgraph") {
+			return nil, fmt.Errorf("subgraphs are not supported")
+		}
+
+		parts := strings.Split(statement, operator)
+		var ids []string
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if !dotIDPattern.MatchString(part) {
+				return nil, fmt.Errorf("unsupported statement %q", statement)
+			}
+			ids = append(ids, unquoteDot(part))
+		}
+
+		if len(ids) == 1 {
+			graph.node(ids[0], attributes["label"], dotShape(attributes["shape"]))
+			continue
+		}
+		for i := 0; i+1 < len(ids); i++ {
+			graph.node(ids[i], "", "")
+			graph.node(ids[i+1], "", "")
+			style := "solid"
+			if attributes["style"] == "dashed" || attributes["style"] == "dotted" {
+				style = "dashed"
+			} else if attributes["style"] == "bold" {
+				style = "thick"
+			}
+			graph.Edges = append(graph.Edges, &diagramEdge{
+				From: ids[i], To: ids[i+1], Label: attributes["label"], Style: style, Directed: directed,
+			})
+		}
+	}
+
+	if len(graph.Nodes) == 0 {
+		return nil, fmt.Errorf("diagram has no nodes")
+	}
+	return graph, nil
+}
+
+func unquoteDot(value string) string {
+	if strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) >= 2 {
+		value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+	}
+	return value
+}
+
+func dotShape(shape string) string {
+	switch shape {
+	case "":
+		return ""
+	case "diamond":
+		return "diamond"
+	case "circle", "ellipse", "oval", "doublecircle":
+		return "circle"
+	case "box", "rect", "rectangle", "square", "plaintext", "plain", "none":
+		return "rect"
+	}
+	return "round"
+}
+
+// layoutGraph assigns ranks by longest path (ignoring back edges), orders
+// nodes within each rank by the barycenter of their parents and positions them.
+func layoutGraph(graph *diagramGraph) (width, height float64) {
+	for _, n := range graph.Nodes {
+		n.W = float64(len([]rune(n.Label)))*diagramCharWidth + diagramNodePad
+		n.H = diagramNodeHeight
+		switch n.Shape {
+		case "diamond":
+			n.W *= 1.4
+			n.H *= 1.4
+		case "circle":
+			if n.W > n.H {
+				n.H = n.W
+			} else {
+				n.W = n.H
+			}
+		}
+	}
+
+	// Drop edges that close a cycle so ranking terminates
+	index := make(map[string]int)
+	for i, n := range graph.Nodes {
+		index[n.ID] = i
+	}
+	outgoing := make(map[string][]string)
+	for _, e := range graph.Edges {
+		outgoing[e.From] = append(outgoing[e.From], e.To)
+	}
+	state := make(map[string]int)
+	forward := make(map[[2]string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = 1
+		for _, to := range outgoing[id] {
+			if state[to] == 0 {
+				forward[[2]string{id, to}] = true
+				visit(to)
+			} else if state[to] == 2 {
+				forward[[2]string{id, to}] = true
+			}
+		}
+		state[id] = 2
+	}
+	for _, n := range graph.Nodes {
+		if state[n.ID] == 0 {
+			visit(n.ID)
+		}
+	}
+
+	// Longest path ranking
+	for changed := true; changed; {
+		changed = false
+		for _, e := range graph.Edges {
+			if !forward[[2]string{e.From, e.To}] {
+				continue
+			}
+			from, to := graph.byID[e.From], graph.byID[e.To]
+			if to.Rank < from.Rank+1 {
+				to.Rank = from.Rank + 1
+				changed = true
+			}
+		}
+	}
+
+	ranks := make(map[int][]*diagramNode)
+	maxRank := 0
+	for _, n := range graph.Nodes {
+		ranks[n.Rank] = append(ranks[n.Rank], n)
+		if n.Rank > maxRank {
+			maxRank = n.Rank
+		}
+	}
+	for r := 0; r <= maxRank; r++ {
+		for i, n := range ranks[r] {
+			n.Order = float64(i)
+		}
+	}
+
+	// Barycenter sweeps reduce edge crossings
+	for sweep := 0; sweep < 4; sweep++ {
+		for r := 1; r <= maxRank; r++ {
+			for _, n := range ranks[r] {
+				sum, count := 0.0, 0
+				for _, e := range graph.Edges {
+					if e.To == n.ID && graph.byID[e.From].Rank < r {
+						sum += graph.byID[e.From].Order
+						count++
+					}
+				}
+				if count > 0 {
+					n.Order = sum / float64(count)
+				}
+			}
+			sort.SliceStable(ranks[r], func(i, j int) bool { return ranks[r][i].Order < ranks[r][j].Order })
+			for i, n := range ranks[r] {
+				n.Order = float64(i)
+			}
+		}
+	}
+
+	// Position ranks along the main axis and nodes across it, centring each rank
+	rankSize := make([]float64, maxRank+1)
+	rankSpan := make([]float64, maxRank+1)
+	for r := 0; r <= maxRank; r++ {
+		for i, n := range ranks[r] {
+			main, cross := n.H, n.W
+			if graph.Horizontal {
+				main, cross = n.W, n.H
+			}
+			if main > rankSize[r] {
+				rankSize[r] = main
+			}
+			if i > 0 {
+				rankSpan[r] += diagramNodeGap
+			}
+			rankSpan[r] += cross
+		}
+	}
+	maxSpan := 0.0
+	for _, span := range rankSpan {
+		if span > maxSpan {
+			maxSpan = span
+		}
+	}
+
+	mainPos := float64(diagramMargin)
+	for r := 0; r <= maxRank; r++ {
+		crossPos := diagramMargin + (maxSpan-rankSpan[r])/2
+		for _, n := range ranks[r] {
+			if graph.Horizontal {
+				n.X = mainPos + rankSize[r]/2
+				n.Y = crossPos + n.H/2
+				crossPos += n.H + diagramNodeGap
+			} else {
+				n.X = crossPos + n.W/2
+				n.Y = mainPos + rankSize[r]/2
+				crossPos += n.W + diagramNodeGap
+			}
+		}
+		mainPos += rankSize[r] + diagramRankGap
+	}
+	mainPos += diagramMargin - diagramRankGap
+
+	if graph.Horizontal {
+		return mainPos, maxSpan + 2*diagramMargin
+	}
+	return maxSpan + 2*diagramMargin, mainPos
+}
+
+// boundaryPoint returns where the line from the node centre towards (tx, ty)
+// leaves the node's bounding shape.
+func boundaryPoint(n *diagramNode, tx, ty float64) (float64, float64) {
+	dx, dy := tx-n.X, ty-n.Y
+	if dx == 0 && dy == 0 {
+		return n.X, n.Y
+	}
+	hw, hh := n.W/2, n.H/2
+	var scale float64
+	switch n.Shape {
+	case "diamond":
+		scale = 1 / (math.Abs(dx)/hw + math.Abs(dy)/hh)
+	case "circle":
+		scale = hw / math.Hypot(dx, dy)
+	default:
+		// Division by zero gives +Inf, which Min ignores
+		scale = math.Min(hw/math.Abs(dx), hh/math.Abs(dy))
+	}
+	return n.X + dx*scale, n.Y + dy*scale
+}
+
+func svgOpen(out *strings.Builder, id string, width, height float64, label string) {
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" role="img" aria-label="%s" class="diagram-svg">`,
+		width, height, width, height, template.HTMLEscapeString(label))
+	fmt.Fprintf(out, `<defs><marker id="%s-arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="currentColor"/></marker></defs>`, id)
+	fmt.Fprintf(out, `<g font-family="Inter, sans-serif" font-size="%d" fill="none" stroke="currentColor">`, diagramFontSize)
+}
+
+func svgText(out *strings.Builder, x, y float64, text string) {
+	fmt.Fprintf(out, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="middle" fill="currentColor" stroke="none">%s</text>`,
+		x, y, template.HTMLEscapeString(text))
+}
+
+func edgeAttributes(style string) string {
+	switch style {
+	case "dashed":
+		return ` stroke-dasharray="5,4"`
+	case "thick":
+		return ` stroke-width="2.5"`
+	}
+	return ""
+}
+
+func renderGraphSVG(graph *diagramGraph, id string) string {
+	width, height := layoutGraph(graph)
+
+	var labels []string
+	for _, n := range graph.Nodes {
+		labels = append(labels, n.Label)
+	}
+
+	var out strings.Builder
+	svgOpen(&out, id, width, height, "Diagram: "+strings.Join(labels, ", "))
+
+	for _, e := range graph.Edges {
+		from, to := graph.byID[e.From], graph.byID[e.To]
+		x1, y1 := boundaryPoint(from, to.X, to.Y)
+		x2, y2 := boundaryPoint(to, from.X, from.Y)
+		marker := ""
+		if e.Directed {
+			marker = fmt.Sprintf(` marker-end="url(#%s-arrow)"`, id)
+		}
+		fmt.Fprintf(&out, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"%s%s/>`, x1, y1, x2, y2, edgeAttributes(e.Style), marker)
+		if e.Label != "" {
+			mx, my := (x1+x2)/2, (y1+y2)/2
+			w := float64(len([]rune(e.Label)))*diagramCharWidth + 8
+			fmt.Fprintf(&out, `<rect x="%.1f" y="%.1f" width="%.1f" height="20" fill="var(--diagram-background, #fff)" stroke="none"/>`, mx-w/2, my-10, w)
+			svgText(&out, mx, my, e.Label)
+		}
+	}
+
+	for _, n := range graph.Nodes {
+		switch n.Shape {
+		case "diamond":
+			fmt.Fprintf(&out, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f"/>`,
+				n.X, n.Y-n.H/2, n.X+n.W/2, n.Y, n.X, n.Y+n.H/2, n.X-n.W/2, n.Y)
+		case "circle":
+			fmt.Fprintf(&out, `<circle cx="%.1f" cy="%.1f" r="%.1f"/>`, n.X, n.Y, n.W/2)
+		case "round":
+			fmt.Fprintf(&out, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="16"/>`, n.X-n.W/2, n.Y-n.H/2, n.W, n.H)
+		default:
+			fmt.Fprintf(&out, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="3"/>`, n.X-n.W/2, n.Y-n.H/2, n.W, n.H)
+		}
+		svgText(&out, n.X, n.Y, n.Label)
+	}
+
+	out.WriteString(`</g></svg>`)
+	return out.String()
+}
+
+// sequenceDiagram is the parsed form of a Mermaid sequenceDiagram.
+type sequenceDiagram struct {
+	Participants []*sequenceParticipant
+	Steps        []sequenceStep
+	byID         map[string]*sequenceParticipant
+}
+
+type sequenceParticipant struct {
+	ID    string
+	Label string
+	X     float64
+}
+
+type sequenceStep struct {
+	From, To string
+	Text     string
+	Dashed   bool
+	Arrow    bool
+	Note     bool
+}
+
+func (s *sequenceDiagram) participant(id, label string) *sequenceParticipant {
+	p, ok := s.byID[id]
+	if !ok {
+		p = &sequenceParticipant{ID: id, Label: id}
+		s.byID[id] = p
+		s.Participants = append(s.Participants, p)
+	}
+	if label != "" {
+		p.Label = label
+	}
+	return p
+}
+
+var (
+	seqParticipantPattern = regexp.MustCompile(`^(?:participant|actor)\s+([A-Za-z0-9_]+)(?:\s+as\s+(.+))?$`)
+	seqMessagePattern     = regexp.MustCompile(`^([A-Za-z0-9_]+)\s*(-->>|->>|-->|->|--x|-x)\s*([A-Za-z0-9_]+)\s*:\s*(.*)$`)
+	seqNotePattern        = regexp.MustCompile(`^[Nn]ote\s+(over|left of|right of)\s+([A-Za-z0-9_]+)(?:\s*,\s*([A-Za-z0-9_]+))?\s*:\s*(.*)$`)
+)
+
+// parseSequence parses participants, messages and notes of a Mermaid
+// sequenceDiagram.
+func parseSequence(source string) (*sequenceDiagram, error) {
+	seq := &sequenceDiagram{byID: make(map[string]*sequenceParticipant)}
+	lines := strings.Split(strings.TrimSpace(source), "\n")
+
+	for i, line := range lines[1:] {
+		lineNo := i + 2
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%%") || line == "autonumber" {
+			continue
+		}
+
+		if match := seqParticipantPattern.FindStringSubmatch(line); match != nil {
+			seq.participant(match[1], strings.TrimSpace(match[2]))
+			continue
+		}
+		if match := seqMessagePattern.FindStringSubmatch(line); match != nil {
+			seq.participant(match[1], "")
+			seq.participant(match[3], "")
+			seq.Steps = append(seq.Steps, sequenceStep{
+				From:   match[1],
+				To:     match[3],
+				Text:   strings.TrimSpace(match[4]),
+				Dashed: strings.HasPrefix(match[2], "--"),
+				Arrow:  strings.HasSuffix(match[2], ">>"),
+			})
+			continue
+		}
+		if match := seqNotePattern.FindStringSubmatch(line); match != nil {
+			seq.participant(match[2], "")
+			to := match[2]
+			if match[3] != "" {
+				seq.participant(match[3], "")
+				to = match[3]
+			}
+			seq.Steps = append(seq.Steps, sequenceStep{From: match[2], To: to, Text: strings.TrimSpace(match[4]), Note: true})
+			continue
+		}
+		return nil, fmt.Errorf("line %d: unsupported syntax %q", lineNo, line)
+	}
+
+	if len(seq.Participants) == 0 {
+		return nil, fmt.Errorf("diagram has no participants")
+	}
+	return seq, nil
+}
+
+func renderSequenceSVG(seq *sequenceDiagram, id string) string {
+	const rowHeight = 44
+
+	// Space participants so the longest message between neighbours fits
+	gap := 160.0
+	for _, step := range seq.Steps {
+		if w := float64(len([]rune(step.Text)))*diagramCharWidth + 40; w > gap {
+			gap = w
+		}
+	}
+	boxWidth := 0.0
+	for _, p := range seq.Participants {
+		if w := float64(len([]rune(p.Label)))*diagramCharWidth + diagramNodePad; w > boxWidth {
+			boxWidth = w
+		}
+	}
+	if gap < boxWidth+diagramNodeGap {
+		gap = boxWidth + diagramNodeGap
+	}
+
+	for i, p := range seq.Participants {
+		p.X = diagramMargin + boxWidth/2 + float64(i)*gap
+	}
+	width := seq.Participants[len(seq.Participants)-1].X + boxWidth/2 + diagramMargin
+	top := float64(diagramMargin + diagramNodeHeight)
+	bottom := top + float64(len(seq.Steps)+1)*rowHeight
+	height := bottom + diagramNodeHeight + diagramMargin
+
+	var labels []string
+	for _, p := range seq.Participants {
+		labels = append(labels, p.Label)
+	}
+
+	var out strings.Builder
+	svgOpen(&out, id, width, height, "Sequence diagram: "+strings.Join(labels, ", "))
+
+	for _, p := range seq.Participants {
+		fmt.Fprintf(&out, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke-dasharray="3,3" opacity="0.5"/>`, p.X, top, p.X, bottom)
+		for _, y := range []float64{diagramMargin, bottom} {
+			fmt.Fprintf(&out, `<rect x="%.1f" y="%.1f" width="%.1f" height="%d" rx="3"/>`, p.X-boxWidth/2, y, boxWidth, diagramNodeHeight)
+			svgText(&out, p.X, y+diagramNodeHeight/2, p.Label)
+		}
+	}
+
+	for i, step := range seq.Steps {
+		y := top + float64(i+1)*rowHeight
+		from, to := seq.byID[step.From], seq.byID[step.To]
+
+		if step.Note {
+			left, right := from.X, to.X
+			if left > right {
+				left, right = right, left
+			}
+			w := float64(len([]rune(step.Text)))*diagramCharWidth + 16
+			if right-left+boxWidth/2 > w {
+				w = right - left + boxWidth/2
+			}
+			cx := (left + right) / 2
+			fmt.Fprintf(&out, `<rect x="%.1f" y="%.1f" width="%.1f" height="28" fill="var(--diagram-note, #f5f5f5)"/>`, cx-w/2, y-14, w)
+			svgText(&out, cx, y, step.Text)
+			continue
+		}
+
+		marker := fmt.Sprintf(` marker-end="url(#%s-arrow)"`, id)
+		if !step.Arrow {
+			marker = ""
+		}
+		dash := ""
+		if step.Dashed {
+			dash = ` stroke-dasharray="5,4"`
+		}
+
+		if from == to {
+			// Self message loops out to the right
+			fmt.Fprintf(&out, `<path d="M%.1f,%.1f h30 v14 h-30"%s%s/>`, from.X, y-7, dash, marker)
+			fmt.Fprintf(&out, `<text x="%.1f" y="%.1f" fill="currentColor" stroke="none">%s</text>`, from.X+36, y+4, template.HTMLEscapeString(step.Text))
+			continue
+		}
+
+		fmt.Fprintf(&out, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"%s%s/>`, from.X, y, to.X, y, dash, marker)
+		svgText(&out, (from.X+to.X)/2, y-12, step.Text)
+	}
+
+	out.WriteString(`</g></svg>`)
+	return out.String()
+}
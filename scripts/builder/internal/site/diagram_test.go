@@ -0,0 +1,124 @@
+package site
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describeGraph lists a graph's direction, nodes and edges on one line each.
+func describeGraph(graph *diagramGraph) string {
+	var lines []string
+	if graph.Horizontal {
+		lines = append(lines, "horizontal")
+	}
+	for _, node := range graph.Nodes {
+		lines = append(lines, fmt.Sprintf("node %s %q %s", node.ID, node.Label, node.Shape))
+	}
+	for _, edge := range graph.Edges {
+		lines = append(lines, fmt.Sprintf("edge %s %s %q %s", edge.From, edge.To, edge.Label, edge.Style))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParseFlowchart(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{
+			name:   "semicolons",
+			source: "graph TD;\n  A[Start]-->B{Check};\n  B-- yes -->C;",
+			want: `node A "Start" rect
+node B "Check" diamond
+node C "C" rect
+edge A B "" solid
+edge B C "yes" solid`,
+		},
+		{
+			name:   "one line",
+			source: "flowchart LR; A-->B; B-.->C",
+			want: `horizontal
+node A "A" rect
+node B "B" rect
+node C "C" rect
+edge A B "" solid
+edge B C "" dashed`,
+		},
+		{
+			name:   "semicolon in label",
+			source: "graph TD\n  A[\"one; two\"]-->B(three; four)",
+			want: `node A "one; two" rect
+node B "three; four" round
+edge A B "" solid`,
+		},
+	}
+
+	for _, test := range tests {
+		graph, err := parseFlowchart(test.source)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := describeGraph(graph); got != test.want {
+			t.Errorf("%s: parsed\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseFlowchartErrors(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{"graph XY;\n  A-->B", `line 1: unsupported direction "XY"`},
+		{"graph TD\n  A-->B\n  B-->", "line 3: edge has no target"},
+		{"graph TD; A-->B; B-->", "line 1: edge has no target"},
+	}
+
+	for _, test := range tests {
+		if _, err := parseFlowchart(test.source); err == nil || err.Error() != test.want {
+			t.Errorf("parseFlowchart(%q) error = %v, want %q", test.source, err, test.want)
+		}
+	}
+}
+
+func TestParseDot(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{
+			name:   "statements",
+			source: "digraph {\n  rankdir=LR\n  a [label=\"A\", shape=box];\n  a -> b -> c [style=dashed]\n}",
+			want: `horizontal
+node a "A" rect
+node b "b" rect
+node c "c" rect
+edge a b "" dashed
+edge b c "" dashed`,
+		},
+		{
+			name:   "semicolon in label",
+			source: "digraph { a [label=\"x; y\"]; a -> b [label=\"go; now\"] }",
+			want: `node a "x; y" rect
+node b "b" rect
+edge a b "go; now" solid`,
+		},
+		{
+			name:   "attributes over lines",
+			source: "graph {\n  a [label=\"A\",\n     shape=diamond]\n  a -- b\n}",
+			want: `node a "A" diamond
+node b "b" rect
+edge a b "" solid`,
+		},
+	}
+
+	for _, test := range tests {
+		graph, err := parseDot(test.source)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := describeGraph(graph); got != test.want {
+			t.Errorf("%s: parsed\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,578 @@
+package site
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ExportOptions controls an export of the published posts for offline
+// reading.
+type ExportOptions struct {
+	// Format is epub or zip
+	Format string
+
+	// File is written to, defaulting to <site title>.<format>
+	File string
+}
+
+// exportBook is the published posts as chapters, with the images they use.
+type exportBook struct {
+	Title       string
+	Author      string
+	Description string
+	Language    string
+	Identifier  string
+	URL         string
+	Modified    time.Time
+	Chapters    []*exportChapter
+	Images      []*exportImage
+
+	// XML is set for EPUB, whose pages must be XHTML
+	XML bool
+}
+
+type exportChapter struct {
+	ID          string
+	File        string
+	Order       int
+	Title       string
+	Description string
+	Created     string
+	ReadingTime int
+	LinkURL     string
+	LinkHost    string
+	Quote       string
+	Body        string
+	Properties  string
+	Previous    *exportChapter
+	Next        *exportChapter
+}
+
+type exportImage struct {
+	ID        string
+	File      string
+	MediaType string
+	source    string
+}
+
+// exportImageTypes are the image formats EPUB readers have to support.
+var exportImageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+var htmlTagNamePattern = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9]*)`)
+
+// exportCSS styles the exported pages in place of the site's stylesheet,
+// which depends on web fonts and the site's layout.
+const exportCSS = `body { font-family: Georgia, serif; line-height: 1.6; margin: 0 auto; max-width: 40em; padding: 0 1em; }
+h1, h2, h3 { font-family: Helvetica, Arial, sans-serif; line-height: 1.25; }
+img, svg { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; font-size: 0.85em; }
+blockquote { margin-left: 0; padding-left: 1em; border-left: 3px solid #ccc; color: #444; }
+figure { margin: 1em 0; }
+figcaption, .meta { font-size: 0.85em; color: #666; }
+.toc li { margin-bottom: 0.5em; }
+.pager { display: flex; justify-content: space-between; margin: 2em 0; }
+`
+
+// Export writes the published posts as an EPUB, with a chapter per post
+// and a table of contents, or as a zip of HTML pages. Images the posts use
+// are included; remote images become links.
+func (g *Generator) Export(opts ExportOptions) error {
+	if opts.Format != "epub" && opts.Format != "zip" {
+		return fmt.Errorf("unknown export format %q (use epub or zip)", opts.Format)
+	}
+	if opts.File == "" {
+		opts.File = filepath.Join(g.rootDir, g.slugify(g.config.Title)+"."+opts.Format)
+	}
+
+	posts, err := g.loadPosts()
+	if err != nil {
+		return fmt.Errorf("failed to read posts: %w", err)
+	}
+	items, err := g.loadLibraryItems()
+	if err != nil {
+		return fmt.Errorf("failed to read library: %w", err)
+	}
+	if err := g.resolveLinks(posts, items); err != nil {
+		return fmt.Errorf("failed to resolve links:\n%w", err)
+	}
+
+	book, err := g.exportBook(posts, opts.Format == "epub")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(opts.File)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", opts.File, err)
+	}
+	archive := zip.NewWriter(file)
+	if opts.Format == "epub" {
+		err = writeEPUB(archive, book)
+	} else {
+		err = writeHTMLArchive(archive, book, strings.TrimSuffix(filepath.Base(opts.File), filepath.Ext(opts.File)))
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", opts.File, err)
+	}
+
+	fmt.Printf("Exported %d posts and %d images to %s\n", len(book.Chapters), len(book.Images), opts.File)
+	return nil
+}
+
+// exportBook renders the posts oldest first, so the book reads in the order
+// it was written.
+func (g *Generator) exportBook(posts []*Post, xml bool) (*exportBook, error) {
+	sortPostsByDate(posts)
+
+	book := &exportBook{
+		Title:       g.config.Title,
+		Author:      g.config.Author,
+		Description: g.config.Description,
+		Language:    g.siteLanguage(),
+		Identifier:  exportIdentifier(g.config.BaseURL),
+		URL:         g.absURL("/"),
+		XML:         xml,
+	}
+
+	ext := ".html"
+	if xml {
+		ext = ".xhtml"
+	}
+	chapterFiles := make(map[string]string)
+	for i, post := range posts {
+		chapter := &exportChapter{
+			ID:          fmt.Sprintf("chapter-%d", i+1),
+			File:        "posts/" + post.Slug + ext,
+			Order:       i + 1,
+			Title:       post.Title,
+			Description: post.Description,
+			Created:     post.Created,
+			ReadingTime: post.ReadingTime,
+			Quote:       post.Quote,
+		}
+		if post.Type == "link" && post.LinkURL != "" {
+			chapter.LinkURL = post.LinkURL
+			chapter.LinkHost = linkHost(post.LinkURL)
+		}
+		if i > 0 {
+			chapter.Previous = book.Chapters[i-1]
+			book.Chapters[i-1].Next = chapter
+		}
+		book.Chapters = append(book.Chapters, chapter)
+		chapterFiles[g.postURL(post)] = path.Base(chapter.File)
+	}
+	book.Modified = feedUpdated(posts)
+	if book.Modified.IsZero() {
+		book.Modified = time.Now()
+	}
+	book.Modified = book.Modified.UTC()
+
+	images := make(map[string]*exportImage)
+	for i, post := range posts {
+		content, err := g.renderPostContent(post)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render post %s: %w", post.Slug, err)
+		}
+
+		writer := &xhtmlWriter{
+			link: func(href string) string {
+				target := strings.TrimPrefix(href, g.config.BaseURL)
+				page, fragment, _ := strings.Cut(target, "#")
+				if file, ok := chapterFiles[page]; ok {
+					if fragment != "" {
+						return file + "#" + fragment
+					}
+					return file
+				}
+				if strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//") {
+					return g.absURL(href)
+				}
+				return href
+			},
+			image: func(src string) string {
+				return g.exportImage(book, images, src)
+			},
+		}
+		chapter := book.Chapters[i]
+		chapter.Body = writer.convert(content)
+
+		var properties []string
+		if strings.Contains(chapter.Body, "<svg") {
+			properties = append(properties, "svg")
+		}
+		if strings.Contains(chapter.Body, "<math") {
+			properties = append(properties, "mathml")
+		}
+		chapter.Properties = strings.Join(properties, " ")
+	}
+
+	return book, nil
+}
+
+// exportImage adds a local image to the book and returns its path from a
+// chapter, or "" when it isn't on disk or isn't a format readers support.
+func (g *Generator) exportImage(book *exportBook, images map[string]*exportImage, src string) string {
+	src = strings.TrimPrefix(src, g.config.BaseURL)
+	if strings.Contains(src, "://") || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "data:") {
+		return ""
+	}
+	src, _, _ = strings.Cut(src, "?")
+	name := path.Clean("/" + src)[1:]
+	if image, ok := images[name]; ok {
+		return "../" + image.File
+	}
+
+	mediaType := exportImageTypes[strings.ToLower(path.Ext(name))]
+	source := filepath.Join(g.rootDir, filepath.FromSlash(name))
+	if _, err := os.Stat(source); mediaType == "" || err != nil {
+		return ""
+	}
+
+	image := &exportImage{
+		ID:        fmt.Sprintf("image-%d", len(book.Images)+1),
+		File:      "images/" + strings.TrimPrefix(name, "images/"),
+		MediaType: mediaType,
+		source:    source,
+	}
+	images[name] = image
+	book.Images = append(book.Images, image)
+	return "../" + image.File
+}
+
+// exportIdentifier is a UUID derived from the site's URL, so every export of
+// the site is recognised as the same book.
+func exportIdentifier(baseURL string) string {
+	sum := sha1.Sum([]byte(baseURL))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// xhtmlWriter writes rendered HTML back out as well-formed XHTML, pointing
+// links and images at their place in the export.
+type xhtmlWriter struct {
+	out   strings.Builder
+	link  func(href string) string
+	image func(src string) string
+}
+
+func (w *xhtmlWriter) convert(content string) string {
+	for _, node := range parseHTMLTree(content).children {
+		w.write(node)
+	}
+	return strings.TrimSpace(w.out.String())
+}
+
+func (w *xhtmlWriter) write(node *htmlNode) {
+	if node.tag == "" {
+		w.out.WriteString(escapeXML(html.UnescapeString(node.text)))
+		return
+	}
+	if droppedElements[node.tag] {
+		return
+	}
+
+	// Tag and attribute names keep their case for SVG
+	name := node.tag
+	if match := htmlTagNamePattern.FindStringSubmatch(node.raw); match != nil {
+		name = match[1]
+	}
+	rawAttrs := strings.TrimSuffix(strings.TrimSuffix(node.raw[len(name)+1:], ">"), "/")
+
+	attrs := make(map[string]string)
+	var order []string
+	for _, match := range htmlAttrsPattern.FindAllStringSubmatch(rawAttrs, -1) {
+		attr := match[1]
+		if _, seen := attrs[attr]; seen {
+			continue
+		}
+		value := html.UnescapeString(match[2] + match[3] + match[4])
+		if !strings.Contains(match[0], "=") {
+			value = attr
+		}
+		attrs[attr] = value
+		order = append(order, attr)
+	}
+
+	switch node.tag {
+	case "a":
+		if href, ok := attrs["href"]; ok {
+			attrs["href"] = w.link(href)
+		}
+	case "img":
+		src := w.image(attrs["src"])
+		if src == "" {
+			// Readers won't load remote images, so link to them instead
+			alt := attrs["alt"]
+			if alt == "" {
+				alt = "Image"
+			}
+			if strings.HasPrefix(attrs["src"], "http") {
+				fmt.Fprintf(&w.out, `<a href="%s">%s</a>`, escapeXML(attrs["src"]), escapeXML(alt))
+			} else {
+				w.out.WriteString(escapeXML(alt))
+			}
+			return
+		}
+		attrs["src"] = src
+		delete(attrs, "srcset")
+		delete(attrs, "sizes")
+		delete(attrs, "loading")
+	case "svg":
+		if _, ok := attrs["xmlns"]; !ok {
+			attrs["xmlns"] = "http://www.w3.org/2000/svg"
+			order = append(order, "xmlns")
+		}
+	case "math":
+		if _, ok := attrs["xmlns"]; !ok {
+			attrs["xmlns"] = "http://www.w3.org/1998/Math/MathML"
+			order = append(order, "xmlns")
+		}
+	}
+
+	w.out.WriteString("<" + name)
+	for _, attr := range order {
+		if value, ok := attrs[attr]; ok {
+			fmt.Fprintf(&w.out, ` %s="%s"`, attr, escapeXML(value))
+		}
+	}
+	if voidElements[node.tag] {
+		w.out.WriteString("/>")
+		return
+	}
+	w.out.WriteString(">")
+	for _, child := range node.children {
+		w.write(child)
+	}
+	w.out.WriteString("</" + name + ">")
+}
+
+func escapeXML(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(text)
+}
+
+var exportFuncs = template.FuncMap{"xml": escapeXML}
+
+const exportChapterTemplate = `{{if .Book.XML}}<?xml version="1.0" encoding="UTF-8"?>
+{{end}}<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" lang="{{.Book.Language}}" xml:lang="{{.Book.Language}}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{xml .Chapter.Title}}</title>
+  <link rel="stylesheet" type="text/css" href="../style.css"/>
+</head>
+<body>
+  <article>
+    <h1>{{xml .Chapter.Title}}</h1>
+    <p class="meta">{{with .Chapter.Created}}{{xml .}} · {{end}}{{.Chapter.ReadingTime}} min read</p>
+    {{- with .Chapter.LinkURL}}
+    <p class="meta">Link: <a href="{{xml .}}">{{xml $.Chapter.LinkHost}}</a></p>
+    {{- end}}
+    {{- with .Chapter.Quote}}
+    <blockquote><p>{{xml .}}</p></blockquote>
+    {{- end}}
+    {{.Chapter.Body}}
+  </article>
+  {{- if not .Book.XML}}
+  <nav class="pager">
+    {{with .Chapter.Previous}}<a href="../{{.File}}">← {{xml .Title}}</a>{{else}}<span></span>{{end}}
+    <a href="../index.html">Contents</a>
+    {{with .Chapter.Next}}<a href="../{{.File}}">{{xml .Title}} →</a>{{else}}<span></span>{{end}}
+  </nav>
+  {{- end}}
+</body>
+</html>
+`
+
+const exportContentsTemplate = `{{if .XML}}<?xml version="1.0" encoding="UTF-8"?>
+{{end}}<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml"{{if .XML}} xmlns:epub="http://www.idpf.org/2007/ops"{{end}} lang="{{.Language}}" xml:lang="{{.Language}}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{xml .Title}}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+  <h1>{{xml .Title}}</h1>
+  <p class="meta">{{xml .Author}} · <a href="{{xml .URL}}">{{xml .URL}}</a></p>
+  <p>{{xml .Description}}</p>
+  <nav{{if .XML}} epub:type="toc"{{end}} id="toc">
+    <h2>Contents</h2>
+    <ol class="toc">
+      {{- range .Chapters}}
+      <li><a href="{{.File}}">{{xml .Title}}</a></li>
+      {{- end}}
+    </ol>
+  </nav>
+</body>
+</html>
+`
+
+const exportPackageTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="{{.Language}}">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{xml .Identifier}}</dc:identifier>
+    <dc:title>{{xml .Title}}</dc:title>
+    <dc:creator>{{xml .Author}}</dc:creator>
+    <dc:language>{{.Language}}</dc:language>
+    <dc:description>{{xml .Description}}</dc:description>
+    <dc:source>{{xml .URL}}</dc:source>
+    <meta property="dcterms:modified">{{.Modified.Format "2006-01-02T15:04:05Z"}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+    {{- range .Chapters}}
+    <item id="{{.ID}}" href="{{.File}}" media-type="application/xhtml+xml"{{with .Properties}} properties="{{.}}"{{end}}/>
+    {{- end}}
+    {{- range .Images}}
+    <item id="{{.ID}}" href="{{xml .File}}" media-type="{{.MediaType}}"/>
+    {{- end}}
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="nav"/>
+    {{- range .Chapters}}
+    <itemref idref="{{.ID}}"/>
+    {{- end}}
+  </spine>
+</package>
+`
+
+// exportNCXTemplate is the table of contents older EPUB 2 readers use.
+const exportNCXTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="{{xml .Identifier}}"/>
+  </head>
+  <docTitle><text>{{xml .Title}}</text></docTitle>
+  <navMap>
+    {{- range .Chapters}}
+    <navPoint id="nav-{{.ID}}" playOrder="{{.Order}}">
+      <navLabel><text>{{xml .Title}}</text></navLabel>
+      <content src="{{.File}}"/>
+    </navPoint>
+    {{- end}}
+  </navMap>
+</ncx>
+`
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="EPUB/package.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func writeEPUB(archive *zip.Writer, book *exportBook) error {
+	// The mimetype comes first and uncompressed so readers can sniff it
+	header := &zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: book.Modified}
+	w, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	if err := writeExportFile(archive, book, "META-INF/container.xml", epubContainer); err != nil {
+		return err
+	}
+	return writeExportPages(archive, book, "EPUB/", map[string]string{
+		"package.opf": exportPackageTemplate,
+		"toc.ncx":     exportNCXTemplate,
+		"nav.xhtml":   exportContentsTemplate,
+	})
+}
+
+// writeHTMLArchive writes the pages into a folder named after the archive,
+// with index.html as the table of contents.
+func writeHTMLArchive(archive *zip.Writer, book *exportBook, name string) error {
+	return writeExportPages(archive, book, name+"/", map[string]string{
+		"index.html": exportContentsTemplate,
+	})
+}
+
+// writeExportPages writes the chapters, images and stylesheet under dir,
+// along with the given pages about the whole book.
+func writeExportPages(archive *zip.Writer, book *exportBook, dir string, pages map[string]string) error {
+	names := make([]string, 0, len(pages))
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeExportTemplate(archive, book, dir+name, pages[name], book); err != nil {
+			return err
+		}
+	}
+	if err := writeExportFile(archive, book, dir+"style.css", exportCSS); err != nil {
+		return err
+	}
+
+	for _, chapter := range book.Chapters {
+		data := map[string]interface{}{"Book": book, "Chapter": chapter}
+		if err := writeExportTemplate(archive, book, dir+chapter.File, exportChapterTemplate, data); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range book.Images {
+		content, err := os.ReadFile(image.source)
+		if err != nil {
+			return fmt.Errorf("failed to read image %s: %w", image.source, err)
+		}
+		if err := writeExportFile(archive, book, dir+image.File, string(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeExportTemplate(archive *zip.Writer, book *exportBook, name, tmpl string, data interface{}) error {
+	t, err := template.New(name).Funcs(exportFuncs).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse template for %s: %w", name, err)
+	}
+	var content strings.Builder
+	if err := t.Execute(&content, data); err != nil {
+		return fmt.Errorf("failed to execute template for %s: %w", name, err)
+	}
+	return writeExportFile(archive, book, name, content.String())
+}
+
+// writeExportFile adds a file dated with the book, so exporting unchanged
+// posts gives an identical archive.
+func writeExportFile(archive *zip.Writer, book *exportBook, name, content string) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: book.Modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, content)
+	return err
+}
@@ -0,0 +1,355 @@
+package site
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// feedLength is how many of the newest posts each feed carries.
+const feedLength = 20
+
+const (
+	rssFeedName  = "feed.xml"
+	jsonFeedName = "feed.json"
+)
+
+// The homepage's feed discovery links are generated between these markers.
+const (
+	homepageFeedsStart = "<!-- feeds:start -->"
+	homepageFeedsEnd   = "<!-- feeds:end -->"
+)
+
+// postFeed is a list of posts published as both RSS and JSON Feed: the
+// whole site, one section or one tag.
+type postFeed struct {
+	Title       string
+	Description string
+
+	// Dir is the URL of the directory feed.xml and feed.json are written to
+	Dir string
+
+	// Page is the URL of the page listing the same posts
+	Page string
+
+	// Posts are newest first
+	Posts []*Post
+}
+
+// feedLink is a feed as advertised by a <link rel="alternate"> in a page's head.
+type feedLink struct {
+	Title string
+	Type  string
+	URL   string
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Content string     `xml:"xmlns:content,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	Language      string      `xml:"language,omitempty"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	Self          rssAtomLink `xml:"atom:link"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"content:encoded"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// jsonFeed is a JSON Feed 1.1 document, whose keys are set by the spec.
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	ExternalURL   string   `json:"external_url,omitempty"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html"`
+	Summary       string   `json:"summary,omitempty"`
+	Image         string   `json:"image,omitempty"`
+	DatePublished string   `json:"date_published,omitempty"`
+	DateModified  string   `json:"date_modified,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// pageDir is the directory beneath a page: the page's own directory for
+// /tags/go/, or tags/go/ beside /tags/go.html. A list's feeds and later
+// pages go there.
+func pageDir(url string) string {
+	if strings.HasSuffix(url, "/") {
+		return url
+	}
+	return strings.TrimSuffix(url, ".html") + "/"
+}
+
+// collectFeeds is the site feed followed by a feed for each section and tag.
+func (g *Generator) collectFeeds(posts []*Post) []*postFeed {
+	feeds := []*postFeed{{
+		Title:       g.config.Title,
+		Description: g.config.Description,
+		Dir:         "/",
+		Page:        "/",
+		Posts:       newestFirst(posts),
+	}}
+	for _, list := range g.taxonomyLists(posts) {
+		feeds = append(feeds, &postFeed{
+			Title:       g.config.Title + " - " + list.Title,
+			Description: list.Description,
+			Dir:         pageDir(list.URL),
+			Page:        list.URL,
+			Posts:       list.Posts,
+		})
+	}
+	return feeds
+}
+
+// feedLinks are the discovery links for a feed, RSS first.
+func (g *Generator) feedLinks(feed *postFeed) []feedLink {
+	return []feedLink{
+		{Title: feed.Title, Type: "application/rss+xml", URL: g.absURL(feed.Dir + rssFeedName)},
+		{Title: feed.Title + " (JSON)", Type: "application/feed+json", URL: g.absURL(feed.Dir + jsonFeedName)},
+	}
+}
+
+// pageFeeds are the discovery links for the site feed and any section and
+// tag feeds a page belongs to.
+func (g *Generator) pageFeeds(section string, tags []string) []feedLink {
+	if g.feeds == nil {
+		return nil
+	}
+	links := g.feedLinks(g.feeds["/"])
+	dirs := []string{}
+	if g.slugify(section) != "" {
+		dirs = append(dirs, pageDir(g.sectionURL(section)))
+	}
+	for _, tag := range tags {
+		if g.slugify(tag) != "" {
+			dirs = append(dirs, pageDir(g.tagURL(tag)))
+		}
+	}
+	for _, dir := range uniqueStrings(dirs) {
+		if feed := g.feeds[dir]; feed != nil {
+			links = append(links, g.feedLinks(feed)...)
+		}
+	}
+	return links
+}
+
+// feedLinksHTML renders discovery links for pages whose head isn't a template.
+func feedLinksHTML(links []feedLink) string {
+	var out strings.Builder
+	for _, link := range links {
+		fmt.Fprintf(&out, "\n  <link rel=\"alternate\" type=\"%s\" title=\"%s\" href=\"%s\">",
+			link.Type, escapeXML(link.Title), escapeXML(link.URL))
+	}
+	return out.String()
+}
+
+// generateFeeds writes feed.xml and feed.json for the site and every section
+// and tag, and removes the feeds of sections and tags no post uses any more.
+func (g *Generator) generateFeeds(posts []*Post) error {
+	feeds := g.collectFeeds(posts)
+
+	// Each post is rendered once however many feeds it's in
+	rendered := make(map[*Post]string)
+	written := make(map[string]bool)
+	for _, feed := range feeds {
+		posts := feed.Posts
+		if len(posts) > feedLength {
+			posts = posts[:feedLength]
+		}
+		for _, post := range posts {
+			if _, ok := rendered[post]; !ok {
+				content, err := g.renderPostContent(post)
+				if err != nil {
+					return fmt.Errorf("failed to render post %s: %w", post.Slug, err)
+				}
+				rendered[post] = g.absoluteLinks(content)
+			}
+		}
+
+		rss, err := g.rssFeed(feed, posts, rendered)
+		if err != nil {
+			return err
+		}
+		jsonContent, err := g.jsonFeed(feed, posts, rendered)
+		if err != nil {
+			return err
+		}
+		for name, content := range map[string][]byte{rssFeedName: rss, jsonFeedName: jsonContent} {
+			path := filepath.Join(g.rootDir, filepath.FromSlash(strings.TrimPrefix(feed.Dir+name, "/")))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+			}
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			written[path] = true
+		}
+	}
+
+	// Drop the feeds of sections and tags that no longer have posts
+	for _, pattern := range []string{g.config.Permalinks.Sections, g.config.Permalinks.Tags} {
+		dir := pageDir(permalink(pattern, map[string]string{"slug": "*"}))
+		for _, name := range []string{rssFeedName, jsonFeedName} {
+			existing, err := filepath.Glob(filepath.Join(g.rootDir, filepath.FromSlash(strings.TrimPrefix(dir+name, "/"))))
+			if err != nil {
+				return err
+			}
+			for _, path := range existing {
+				if written[path] {
+					continue
+				}
+				if err := os.Remove(path); err != nil {
+					return fmt.Errorf("failed to remove stale %s: %w", path, err)
+				}
+				// Only succeeds once the directory is empty
+				os.Remove(filepath.Dir(path))
+			}
+		}
+	}
+
+	fmt.Printf("Generated %d feeds\n", len(feeds))
+	return nil
+}
+
+func (g *Generator) rssFeed(feed *postFeed, posts []*Post, rendered map[*Post]string) ([]byte, error) {
+	doc := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Content: "http://purl.org/rss/1.0/modules/content/",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        g.absURL(feed.Page),
+			Description: feed.Description,
+			Language:    g.siteLanguage(),
+			Self:        rssAtomLink{Href: g.absURL(feed.Dir + rssFeedName), Rel: "self", Type: "application/rss+xml"},
+			Items:       []rssItem{},
+		},
+	}
+	if updated := feedUpdated(posts); !updated.IsZero() {
+		doc.Channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+
+	for _, post := range posts {
+		url := g.absURL(g.postURL(post))
+		item := rssItem{
+			Title:       post.Title,
+			Link:        url,
+			GUID:        rssGUID{IsPermaLink: "true", Value: url},
+			Description: post.Description,
+			Content:     rendered[post],
+			Categories:  splitTags(post.Tags),
+		}
+		if created, ok := parseDate(post.Created); ok {
+			item.PubDate = created.Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	content, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s%s: %w", feed.Dir, rssFeedName, err)
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+func (g *Generator) jsonFeed(feed *postFeed, posts []*Post, rendered map[*Post]string) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: g.absURL(feed.Page),
+		FeedURL:     g.absURL(feed.Dir + jsonFeedName),
+		Description: feed.Description,
+		Language:    g.siteLanguage(),
+		Authors:     []jsonFeedAuthor{{Name: g.config.Author, URL: g.absURL("/about.html")}},
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, post := range posts {
+		url := g.absURL(g.postURL(post))
+		cover := post.Cover
+		if cover == "" {
+			cover = g.previewPath(post)
+		}
+		item := jsonFeedItem{
+			ID:            url,
+			URL:           url,
+			Title:         post.Title,
+			ContentHTML:   rendered[post],
+			Summary:       post.Description,
+			Image:         g.pageImage(cover),
+			DatePublished: isoDate(post.Created),
+			DateModified:  isoDate(post.Updated),
+			Tags:          splitTags(post.Tags),
+		}
+		if post.Type == "link" {
+			item.ExternalURL = post.LinkURL
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s%s: %w", feed.Dir, jsonFeedName, err)
+	}
+	return append(content, '\n'), nil
+}
+
+// feedUpdated is the newest date among the posts, so unchanged posts give an
+// unchanged feed.
+func feedUpdated(posts []*Post) time.Time {
+	var updated time.Time
+	for _, post := range posts {
+		for _, value := range []string{post.Updated, post.Created} {
+			if date, ok := parseDate(value); ok && date.After(updated) {
+				updated = date
+			}
+		}
+	}
+	return updated
+}
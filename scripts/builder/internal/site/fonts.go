@@ -0,0 +1,189 @@
+package site
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	fontsCSS       = "fonts.css"
+	fontsSubsetDir = "fonts/subset"
+)
+
+// fontExtras are characters the markdown renderer produces from plain text,
+// such as smart quotes and dashes, that may not appear in the sources.
+const fontExtras = " ‘’‚“”„–—…½¼¾•·×÷←→↩©®™°′″"
+
+// fontSources are the files whose text makes up the site, used to decide
+// which glyphs the fonts keep.
+var fontSources = []string{
+	"*.html",
+	"site.json",
+	"posts/*.md",
+	"library/*.md",
+	"library/*.html",
+	"templates/shortcodes/*.html",
+}
+
+// fontFiles are the fonts in fonts/ to self-host.
+func (g *Generator) fontFiles() ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.ttf", "*.otf"} {
+		matches, err := filepath.Glob(filepath.Join(g.rootDir, "fonts", pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// buildFonts subsets the fonts in fonts/ to the characters used across the
+// site and writes fonts.css with an @font-face rule for each. The site's
+// fonts are always self-hosted, so a build without them fails.
+func (g *Generator) buildFonts() error {
+	files, err := g.fontFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no fonts in fonts/, run scripts/fetch-fonts.sh to download Fraunces and Inter")
+	}
+
+	subsetDir := filepath.Join(g.rootDir, filepath.FromSlash(fontsSubsetDir))
+	if err := os.MkdirAll(subsetDir, 0755); err != nil {
+		return fmt.Errorf("failed to create font subset directory: %w", err)
+	}
+
+	runes, err := g.siteRunes()
+	if err != nil {
+		return err
+	}
+
+	type face struct {
+		info fontInfo
+		url  string
+	}
+	var faces []face
+	written := make(map[string]bool)
+
+	for _, path := range files {
+		name := filepath.Base(path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read font %s: %w", name, err)
+		}
+		info, err := readFontInfo(data)
+		if err != nil {
+			return fmt.Errorf("failed to read font %s: %w", name, err)
+		}
+
+		subset, err := subsetFont(data, runes)
+		if errors.Is(err, errNotTrueType) {
+			fmt.Printf("Warning: fonts/%s has no TrueType outlines and was not subset\n", name)
+			subset = data
+		} else if err != nil {
+			return fmt.Errorf("failed to subset font %s: %w", name, err)
+		}
+		woff, err := woffFromSFNT(subset)
+		if err != nil {
+			return fmt.Errorf("failed to convert font %s to WOFF: %w", name, err)
+		}
+
+		// The hash changes whenever the subset does, so cached copies are never stale
+		sum := sha256.Sum256(woff)
+		subsetName := strings.TrimSuffix(name, filepath.Ext(name)) + "." + hex.EncodeToString(sum[:3]) + ".woff"
+		subsetPath := filepath.Join(subsetDir, subsetName)
+		if _, err := os.Stat(subsetPath); os.IsNotExist(err) {
+			if err := os.WriteFile(subsetPath, woff, 0644); err != nil {
+				return fmt.Errorf("failed to write font subset %s: %w", subsetName, err)
+			}
+		}
+		written[subsetName] = true
+
+		faces = append(faces, face{info, fontsSubsetDir + "/" + subsetName})
+	}
+
+	// Remove subsets left over from earlier builds
+	existing, err := filepath.Glob(filepath.Join(subsetDir, "*.woff"))
+	if err != nil {
+		return err
+	}
+	for _, path := range existing {
+		if !written[filepath.Base(path)] {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove old font subset: %w", err)
+			}
+		}
+	}
+
+	var css bytes.Buffer
+	css.WriteString("/* Generated by the site builder from fonts/. Do not edit. */\n")
+	for _, f := range faces {
+		fmt.Fprintf(&css, `
+@font-face {
+  font-family: '%s';
+  font-style: %s;
+  font-weight: %s;
+  font-display: %s;
+  src: url('%s') format('woff');
+}
+`, f.info.Family, f.info.Style, f.info.Weight, g.config.FontDisplay, f.url)
+	}
+
+	cssPath := filepath.Join(g.rootDir, fontsCSS)
+	if existing, err := os.ReadFile(cssPath); err != nil || !bytes.Equal(existing, css.Bytes()) {
+		if err := os.WriteFile(cssPath, css.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", fontsCSS, err)
+		}
+	}
+
+	fmt.Printf("Subset %d fonts to %d characters\n", len(faces), len(runes))
+	return nil
+}
+
+// siteRunes returns every character used in the site's sources along with
+// printable ASCII and the typographic characters the renderer produces.
+func (g *Generator) siteRunes() ([]rune, error) {
+	seen := make(map[rune]bool)
+	for r := rune(0x20); r < 0x7f; r++ {
+		seen[r] = true
+	}
+	for _, r := range fontExtras {
+		seen[r] = true
+	}
+
+	for _, pattern := range fontSources {
+		files, err := filepath.Glob(filepath.Join(g.rootDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			for _, r := range html.UnescapeString(string(content)) {
+				if r >= 0x20 {
+					seen[r] = true
+				}
+			}
+		}
+	}
+
+	runes := make([]rune, 0, len(seen))
+	for r := range seen {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes, nil
+}
@@ -0,0 +1,542 @@
+package site
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// errNotTrueType is returned for fonts without glyf outlines, which the
+// subsetter can't rewrite.
+var errNotTrueType = errors.New("font has no TrueType outlines")
+
+// droppedTables are left out of subsets. GSUB substitutions can reach glyphs
+// that aren't in the subset, so fonts fall back to their default glyphs
+// rather than risk rendering blanks.
+var droppedTables = map[string]bool{
+	"GSUB": true,
+	"DSIG": true,
+	"morx": true,
+	"mort": true,
+}
+
+// fontInfo describes a font file for its @font-face rule.
+type fontInfo struct {
+	Family string
+	Weight string
+	Style  string
+}
+
+// readFontInfo reads the family, weight and style of a font, using the
+// weight axis range for variable fonts.
+func readFontInfo(data []byte) (fontInfo, error) {
+	info := fontInfo{Weight: "400", Style: "normal"}
+
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return info, err
+	}
+	var buf sfnt.Buffer
+	info.Family, err = f.Name(&buf, sfnt.NameIDTypographicFamily)
+	if err != nil || info.Family == "" {
+		info.Family, err = f.Name(&buf, sfnt.NameIDFamily)
+		if err != nil {
+			return info, fmt.Errorf("failed to read family name: %w", err)
+		}
+	}
+
+	_, tables, err := parseSFNT(data)
+	if err != nil {
+		return info, err
+	}
+	if os2 := tables["OS/2"]; len(os2) >= 64 {
+		info.Weight = fmt.Sprint(binary.BigEndian.Uint16(os2[4:]))
+		if binary.BigEndian.Uint16(os2[62:])&1 != 0 {
+			info.Style = "italic"
+		}
+	} else if head := tables["head"]; len(head) >= 46 && binary.BigEndian.Uint16(head[44:])&2 != 0 {
+		info.Style = "italic"
+	}
+
+	if fvar := tables["fvar"]; fvar != nil {
+		weight, err := fvarWeight(fvar)
+		if err != nil {
+			return info, err
+		}
+		if weight != "" {
+			info.Weight = weight
+		}
+	}
+
+	return info, nil
+}
+
+// fvarWeight returns the range of a variable font's weight axis as
+// "low high", or "" if it has no weight axis.
+func fvarWeight(fvar []byte) (string, error) {
+	if len(fvar) < 16 {
+		return "", errors.New("fvar table is truncated")
+	}
+	axesOffset := int(binary.BigEndian.Uint16(fvar[4:]))
+	axisCount := int(binary.BigEndian.Uint16(fvar[8:]))
+	axisSize := int(binary.BigEndian.Uint16(fvar[10:]))
+	if axisCount > 0 && axisSize < 20 {
+		return "", fmt.Errorf("fvar axis records are %d bytes, expected at least 20", axisSize)
+	}
+	if axesOffset+axisCount*axisSize > len(fvar) {
+		return "", errors.New("fvar table is truncated")
+	}
+
+	weight := ""
+	for i := 0; i < axisCount; i++ {
+		axis := fvar[axesOffset+i*axisSize:]
+		if string(axis[:4]) != "wght" {
+			continue
+		}
+		low := int32(binary.BigEndian.Uint32(axis[4:])) >> 16
+		high := int32(binary.BigEndian.Uint32(axis[12:])) >> 16
+		weight = fmt.Sprintf("%d %d", low, high)
+	}
+	return weight, nil
+}
+
+// parseSFNT splits a TrueType or OpenType file into its tables.
+func parseSFNT(data []byte) (uint32, map[string][]byte, error) {
+	if len(data) < 12 {
+		return 0, nil, errors.New("font file is too short")
+	}
+	flavor := binary.BigEndian.Uint32(data)
+	if flavor == 0x74746366 { // ttcf
+		return 0, nil, errors.New("font collections are not supported")
+	}
+
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return 0, nil, errors.New("font table directory is truncated")
+		}
+		tag := string(data[record : record+4])
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > len(data) {
+			return 0, nil, fmt.Errorf("font table %q is truncated", tag)
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+	return flavor, tables, nil
+}
+
+// subsetFont returns a copy of a TrueType font that only has outlines for
+// the given characters. Glyph IDs are kept as they are and unused glyphs are
+// emptied, so tables that refer to glyphs by ID stay valid.
+func subsetFont(data []byte, runes []rune) ([]byte, error) {
+	flavor, tables, err := parseSFNT(data)
+	if err != nil {
+		return nil, err
+	}
+	head, maxp, glyf, loca := tables["head"], tables["maxp"], tables["glyf"], tables["loca"]
+	if glyf == nil || loca == nil {
+		return nil, errNotTrueType
+	}
+	if len(head) < 54 || len(maxp) < 6 {
+		return nil, errors.New("font has an invalid head or maxp table")
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	offsets, err := glyphOffsets(loca, numGlyphs, int16(binary.BigEndian.Uint16(head[50:])) == 1)
+	if err != nil {
+		return nil, err
+	}
+	glyphData := func(gid int) []byte {
+		start, end := offsets[gid], offsets[gid+1]
+		if start >= end || end > len(glyf) {
+			return nil
+		}
+		return glyf[start:end]
+	}
+
+	// Map the characters to glyphs
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf sfnt.Buffer
+	mapping := make(map[rune]uint16)
+	keep := map[int]bool{0: true}
+	for _, r := range runes {
+		gid, err := f.GlyphIndex(&buf, r)
+		if err != nil || gid == 0 {
+			continue
+		}
+		mapping[r] = uint16(gid)
+		keep[int(gid)] = true
+	}
+
+	// Composite glyphs need their components
+	queue := make([]int, 0, len(keep))
+	for gid := range keep {
+		queue = append(queue, gid)
+	}
+	for len(queue) > 0 {
+		gid := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, component := range glyphComponents(glyphData(gid)) {
+			if component < numGlyphs && !keep[component] {
+				keep[component] = true
+				queue = append(queue, component)
+			}
+		}
+	}
+
+	// Rebuild glyf and loca with long offsets
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, 4*(numGlyphs+1))
+	for gid := 0; gid < numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[4*gid:], uint32(newGlyf.Len()))
+		if keep[gid] {
+			newGlyf.Write(glyphData(gid))
+			for newGlyf.Len()%4 != 0 {
+				newGlyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*numGlyphs:], uint32(newGlyf.Len()))
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint16(newHead[50:], 1)
+
+	out := make(map[string][]byte, len(tables))
+	for tag, table := range tables {
+		if !droppedTables[tag] {
+			out[tag] = table
+		}
+	}
+	out["head"] = newHead
+	out["glyf"] = newGlyf.Bytes()
+	out["loca"] = newLoca
+	out["cmap"] = buildCmap(mapping)
+
+	// Glyph names aren't needed on the web
+	if post := tables["post"]; len(post) >= 32 {
+		newPost := append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(newPost, 0x00030000)
+		out["post"] = newPost
+	}
+
+	if gvar := tables["gvar"]; gvar != nil {
+		newGvar, err := subsetGvar(gvar, keep)
+		if err != nil {
+			return nil, err
+		}
+		out["gvar"] = newGvar
+	}
+
+	return buildSFNT(flavor, out), nil
+}
+
+func glyphOffsets(loca []byte, numGlyphs int, long bool) ([]int, error) {
+	offsets := make([]int, numGlyphs+1)
+	for i := range offsets {
+		if long {
+			if 4*i+4 > len(loca) {
+				return nil, errors.New("font loca table is truncated")
+			}
+			offsets[i] = int(binary.BigEndian.Uint32(loca[4*i:]))
+		} else {
+			if 2*i+2 > len(loca) {
+				return nil, errors.New("font loca table is truncated")
+			}
+			offsets[i] = 2 * int(binary.BigEndian.Uint16(loca[2*i:]))
+		}
+	}
+	return offsets, nil
+}
+
+// glyphComponents returns the glyph IDs a composite glyph is built from.
+func glyphComponents(glyph []byte) []int {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+
+	const (
+		argsAreWords   = 0x0001
+		haveScale      = 0x0008
+		moreComponents = 0x0020
+		haveXYScale    = 0x0040
+		haveTwoByTwo   = 0x0080
+	)
+
+	var components []int
+	pos := 10
+	for pos+4 <= len(glyph) {
+		flags := binary.BigEndian.Uint16(glyph[pos:])
+		components = append(components, int(binary.BigEndian.Uint16(glyph[pos+2:])))
+		pos += 4
+		if flags&argsAreWords != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			pos += 2
+		case flags&haveXYScale != 0:
+			pos += 4
+		case flags&haveTwoByTwo != 0:
+			pos += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// buildCmap builds a cmap with a format 4 subtable for the Basic
+// Multilingual Plane and a format 12 subtable for everything.
+func buildCmap(mapping map[rune]uint16) []byte {
+	runes := make([]rune, 0, len(mapping))
+	for r := range mapping {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	// Format 4 segments share a constant delta between code and glyph
+	type segment struct{ start, end, delta int }
+	var segments []segment
+	for _, r := range runes {
+		if r > 0xFFFE {
+			break
+		}
+		delta := int(mapping[r]) - int(r)
+		if n := len(segments); n > 0 && segments[n-1].end == int(r)-1 && segments[n-1].delta == delta {
+			segments[n-1].end = int(r)
+			continue
+		}
+		segments = append(segments, segment{int(r), int(r), delta})
+	}
+	segments = append(segments, segment{0xFFFF, 0xFFFF, 1})
+
+	segCount := len(segments)
+	searchRange := 2 << (bits.Len(uint(segCount)) - 1)
+	var format4 bytes.Buffer
+	put16 := func(b *bytes.Buffer, v int) { binary.Write(b, binary.BigEndian, uint16(v)) }
+	put16(&format4, 4)
+	put16(&format4, 16+8*segCount)
+	put16(&format4, 0)
+	put16(&format4, 2*segCount)
+	put16(&format4, searchRange)
+	put16(&format4, bits.Len(uint(segCount))-1)
+	put16(&format4, 2*segCount-searchRange)
+	for _, s := range segments {
+		put16(&format4, s.end)
+	}
+	put16(&format4, 0)
+	for _, s := range segments {
+		put16(&format4, s.start)
+	}
+	for _, s := range segments {
+		put16(&format4, s.delta&0xFFFF)
+	}
+	for range segments {
+		put16(&format4, 0)
+	}
+
+	// Format 12 groups runs of consecutive codes and glyphs
+	type group struct{ start, end, glyph uint32 }
+	var groups []group
+	for _, r := range runes {
+		gid := uint32(mapping[r])
+		if n := len(groups); n > 0 && groups[n-1].end == uint32(r)-1 && groups[n-1].glyph+groups[n-1].end-groups[n-1].start == gid-1 {
+			groups[n-1].end = uint32(r)
+			continue
+		}
+		groups = append(groups, group{uint32(r), uint32(r), gid})
+	}
+	var format12 bytes.Buffer
+	binary.Write(&format12, binary.BigEndian, []uint16{12, 0})
+	binary.Write(&format12, binary.BigEndian, []uint32{uint32(16 + 12*len(groups)), 0, uint32(len(groups))})
+	for _, g := range groups {
+		binary.Write(&format12, binary.BigEndian, []uint32{g.start, g.end, g.glyph})
+	}
+
+	var cmap bytes.Buffer
+	binary.Write(&cmap, binary.BigEndian, []uint16{0, 2})
+	binary.Write(&cmap, binary.BigEndian, []uint16{3, 1})
+	binary.Write(&cmap, binary.BigEndian, uint32(20))
+	binary.Write(&cmap, binary.BigEndian, []uint16{3, 10})
+	binary.Write(&cmap, binary.BigEndian, uint32(20+format4.Len()))
+	cmap.Write(format4.Bytes())
+	cmap.Write(format12.Bytes())
+	return cmap.Bytes()
+}
+
+// subsetGvar drops the variation data of glyphs that were emptied.
+func subsetGvar(gvar []byte, keep map[int]bool) ([]byte, error) {
+	if len(gvar) < 20 {
+		return nil, errors.New("font has an invalid gvar table")
+	}
+	axisCount := int(binary.BigEndian.Uint16(gvar[4:]))
+	sharedCount := int(binary.BigEndian.Uint16(gvar[6:]))
+	sharedOffset := int(binary.BigEndian.Uint32(gvar[8:]))
+	glyphCount := int(binary.BigEndian.Uint16(gvar[12:]))
+	flags := binary.BigEndian.Uint16(gvar[14:])
+	dataOffset := int(binary.BigEndian.Uint32(gvar[16:]))
+
+	offsets := make([]int, glyphCount+1)
+	for i := range offsets {
+		if flags&1 != 0 {
+			offsets[i] = int(binary.BigEndian.Uint32(gvar[20+4*i:]))
+		} else {
+			offsets[i] = 2 * int(binary.BigEndian.Uint16(gvar[20+2*i:]))
+		}
+	}
+	shared := gvar[sharedOffset : sharedOffset+sharedCount*axisCount*2]
+
+	newSharedOffset := 20 + 4*(glyphCount+1)
+	newDataOffset := newSharedOffset + len(shared)
+
+	var data bytes.Buffer
+	newOffsets := make([]byte, 4*(glyphCount+1))
+	for gid := 0; gid < glyphCount; gid++ {
+		binary.BigEndian.PutUint32(newOffsets[4*gid:], uint32(data.Len()))
+		start, end := dataOffset+offsets[gid], dataOffset+offsets[gid+1]
+		if keep[gid] && start < end && end <= len(gvar) {
+			data.Write(gvar[start:end])
+		}
+	}
+	binary.BigEndian.PutUint32(newOffsets[4*glyphCount:], uint32(data.Len()))
+
+	out := make([]byte, 20, newDataOffset+data.Len())
+	copy(out, gvar[:20])
+	binary.BigEndian.PutUint32(out[8:], uint32(newSharedOffset))
+	binary.BigEndian.PutUint16(out[14:], flags|1)
+	binary.BigEndian.PutUint32(out[16:], uint32(newDataOffset))
+	out = append(out, newOffsets...)
+	out = append(out, shared...)
+	out = append(out, data.Bytes()...)
+	return out, nil
+}
+
+// buildSFNT assembles tables into a font file, filling in the checksums.
+func buildSFNT(flavor uint32, tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	searchRange := 16 << (bits.Len(uint(numTables)) - 1)
+	header := make([]byte, 12+16*numTables)
+	binary.BigEndian.PutUint32(header, flavor)
+	binary.BigEndian.PutUint16(header[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(bits.Len(uint(numTables))-1))
+	binary.BigEndian.PutUint16(header[10:], uint16(16*numTables-searchRange))
+
+	out := header
+	headOffset := -1
+	for i, tag := range tags {
+		table := tables[tag]
+		if tag == "head" && len(table) >= 12 {
+			table = append([]byte(nil), table...)
+			binary.BigEndian.PutUint32(table[8:], 0)
+			headOffset = len(out)
+		}
+		record := out[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], tableChecksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+
+	if headOffset >= 0 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-tableChecksum(out))
+	}
+	return out
+}
+
+func tableChecksum(table []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(table); i += 4 {
+		var word [4]byte
+		copy(word[:], table[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// woffFromSFNT wraps a font file in WOFF 1.0, compressing each table with zlib.
+func woffFromSFNT(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("font file is too short")
+	}
+	flavor := binary.BigEndian.Uint32(data)
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+
+	type entry struct {
+		tag      []byte
+		checksum uint32
+		orig     int
+		data     []byte
+	}
+	entries := make([]entry, 0, numTables)
+	totalSize := 12 + 16*numTables
+	for i := 0; i < numTables; i++ {
+		record := data[12+16*i:]
+		offset := int(binary.BigEndian.Uint32(record[8:]))
+		length := int(binary.BigEndian.Uint32(record[12:]))
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("font table %q is truncated", record[:4])
+		}
+		table := data[offset : offset+length]
+
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		w.Write(table)
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		stored := table
+		if compressed.Len() < len(table) {
+			stored = compressed.Bytes()
+		}
+
+		entries = append(entries, entry{record[:4], binary.BigEndian.Uint32(record[4:]), length, stored})
+		totalSize += (length + 3) &^ 3
+	}
+
+	out := make([]byte, 44+20*numTables)
+	copy(out, "wOFF")
+	binary.BigEndian.PutUint32(out[4:], flavor)
+	binary.BigEndian.PutUint16(out[12:], uint16(numTables))
+	binary.BigEndian.PutUint32(out[16:], uint32(totalSize))
+	binary.BigEndian.PutUint16(out[20:], 1)
+	for i, e := range entries {
+		record := out[44+20*i:]
+		copy(record, e.tag)
+		binary.BigEndian.PutUint32(record[4:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(e.data)))
+		binary.BigEndian.PutUint32(record[12:], uint32(e.orig))
+		binary.BigEndian.PutUint32(record[16:], e.checksum)
+		out = append(out, e.data...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(out[8:], uint32(len(out)))
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package site
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// testFvar builds an fvar table with one 20 byte record per axis tag, each
+// ranging from 100 to 900.
+func testFvar(tags ...string) []byte {
+	fvar := make([]byte, 16+20*len(tags))
+	binary.BigEndian.PutUint16(fvar[4:], 16)
+	binary.BigEndian.PutUint16(fvar[8:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(fvar[10:], 20)
+	for i, tag := range tags {
+		axis := fvar[16+20*i:]
+		copy(axis, tag)
+		binary.BigEndian.PutUint32(axis[4:], 100<<16)
+		binary.BigEndian.PutUint32(axis[8:], 400<<16)
+		binary.BigEndian.PutUint32(axis[12:], 900<<16)
+	}
+	return fvar
+}
+
+func TestFvarWeight(t *testing.T) {
+	weight, err := fvarWeight(testFvar("opsz", "wght"))
+	if err != nil || weight != "100 900" {
+		t.Errorf("fvarWeight = %q, %v, want \"100 900\"", weight, err)
+	}
+
+	weight, err = fvarWeight(testFvar("opsz"))
+	if err != nil || weight != "" {
+		t.Errorf("fvarWeight without a weight axis = %q, %v, want \"\"", weight, err)
+	}
+}
+
+func TestFvarWeightTruncated(t *testing.T) {
+	truncated := testFvar("opsz", "wght")
+	truncated = truncated[:len(truncated)-8]
+
+	shortRecords := testFvar("wght")
+	binary.BigEndian.PutUint16(shortRecords[10:], 4)
+
+	badOffset := testFvar("wght")
+	binary.BigEndian.PutUint16(badOffset[4:], 0xfff0)
+
+	for name, fvar := range map[string][]byte{
+		"short table":   testFvar("wght")[:12],
+		"missing axis":  truncated,
+		"short records": shortRecords,
+		"bad offset":    badOffset,
+	} {
+		if _, err := fvarWeight(fvar); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -6,12 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/html"
-	"github.com/gomarkdown/markdown/parser"
 )
 
 type Post struct {
@@ -22,9 +22,25 @@ type Post struct {
 	Created     string
 	Updated     string
 	Type        string
+	Cover       string
 	Content     string
 	Slug        string
 	Filename    string
+	BodyLine    int
+	WordCount   int
+	ReadingTime int
+	Excerpt     string
+	Series      string
+	SeriesOrder int
+	SeriesInfo  *Series
+	Previous    *Post
+	Next        *Post
+	Related     []*Post
+	Backlinks   []*postLink
+	Aliases     []string
+	LinkURL     string
+	Via         string
+	Quote       string
 }
 
 type LibraryItem struct {
@@ -36,18 +52,37 @@ type LibraryItem struct {
 	Created     string
 	Updated     string
 	Type        string
+	Cover       string
+	Status      string
+	Rating      string
+	Started     string
+	Finished    string
+	ISBN        string
+	Quotes      []string
 	Content     string
 	ID          string
 	Filename    string
+	BodyLine    int
+	Backlinks   []*postLink
 }
 
 type Generator struct {
-	rootDir string
+	rootDir    string
+	config     Config
+	shortcodes *shortcodeSet
+	assets     map[string]string
+	covers     map[string]*bookCover
+
+	// feeds are keyed by directory, for pages to link to the ones they're in
+	feeds map[string]*postFeed
+
+	rebuildHistory bool
 }
 
 func NewGenerator() *Generator {
 	return &Generator{
 		rootDir: ".",
+		config:  defaultConfig(),
 	}
 }
 
@@ -65,43 +100,15 @@ func (g *Generator) formatDate(date time.Time) string {
 	return date.Format("January 2, 2006")
 }
 
-func (g *Generator) CreateNewPost(title, description, tags, section string) error {
-	date := time.Now()
-	formattedDate := g.formatDate(date)
-	slug := g.slugify(title)
-	filename := slug + ".md"
-	postPath := filepath.Join(g.rootDir, "posts", filename)
-
-	// Create posts directory if it doesn't exist
-	postsDir := filepath.Join(g.rootDir, "posts")
-	if err := os.MkdirAll(postsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create posts directory: %w", err)
-	}
-
-	// Create markdown content
-	content := fmt.Sprintf(`---
-title: "%s"
-description: "%s"
-section: "%s"
-tags: "%s"
-created: "%s"
-updated: "%s"
-type: "note"
----
-
-# %s
-
-%s
-
-<!-- Your content here -->
-`, title, description, section, tags, formattedDate, formattedDate, title, description)
-
-	if err := os.WriteFile(postPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write post file: %w", err)
+// parseDate accepts the frontmatter date formats used across posts and library items.
+func parseDate(value string) (time.Time, bool) {
+	layouts := []string{"January 2, 2006", "Jan 2, 2006", "2006-01-02", time.RFC3339}
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return date, true
+		}
 	}
-
-	fmt.Printf("Created new post: %s\n", postPath)
-	return nil
+	return time.Time{}, false
 }
 
 func isProseBlock(content string) bool {
@@ -141,15 +148,18 @@ func fixProsePreCodeBlocks(html string) string {
 
 func (g *Generator) markdownToHTML(mdContent string) (string, error) {
 	// Parse markdown
-	extensions := parser.CommonExtensions
-	parser := parser.NewWithExtensions(extensions)
-	doc := parser.Parse([]byte(mdContent))
+	p := g.newParser()
+	doc := p.Parse([]byte(mdContent))
 
 	// Convert to HTML
-	htmlFlags := html.CommonFlags | html.HrefTargetBlank
-	opts := html.RendererOptions{Flags: htmlFlags}
+	var renderErrs []error
+	opts := g.rendererOptions()
+	opts.RenderNodeHook = chainHooks(g.renderHooks(doc, &renderErrs))
 	renderer := html.NewRenderer(opts)
 	html := markdown.Render(doc, renderer)
+	if len(renderErrs) > 0 {
+		return "", renderErrs[0]
+	}
 
 	// Post-process to fix prose in <pre><code>...</code></pre>
 	fixedHTML := fixProsePreCodeBlocks(string(html))
@@ -164,20 +174,31 @@ func (g *Generator) parseMarkdownFrontmatter(content string) (map[string]string,
 	bodyStart := 0
 
 	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		listKey := ""
 		for i := 1; i < len(lines); i++ {
 			line := strings.TrimSpace(lines[i])
 			if line == "---" {
 				bodyStart = i + 1
 				break
 			}
+			// Items of a block list under an empty key are kept quoted, one
+			// per line, and read with frontmatterList
+			if listKey != "" && strings.HasPrefix(line, "- ") {
+				if metadata[listKey] != "" {
+					metadata[listKey] += "\n"
+				}
+				metadata[listKey] += strings.TrimSpace(line[2:])
+				continue
+			}
+			listKey = ""
 			if strings.Contains(line, ":") {
 				parts := strings.SplitN(line, ":", 2)
 				if len(parts) == 2 {
 					key := strings.TrimSpace(parts[0])
-					value := strings.TrimSpace(parts[1])
-					// Remove leading/trailing quotes (single or double)
-					value = strings.Trim(value, `"'`)
-					metadata[key] = value
+					metadata[key] = unquoteYAML(strings.TrimSpace(parts[1]))
+					if metadata[key] == "" {
+						listKey = key
+					}
 				}
 			}
 		}
@@ -187,11 +208,31 @@ func (g *Generator) parseMarkdownFrontmatter(content string) (map[string]string,
 	return metadata, body, nil
 }
 
-func (g *Generator) generatePostHTML(post *Post) (string, error) {
+// bodyLine returns the 1-based line of the file on which the body starts.
+func bodyLine(content, body string) int {
+	return strings.Count(content, "\n") - strings.Count(body, "\n") + 1
+}
+
+// renderPostContent renders the body of a post to HTML.
+func (g *Generator) renderPostContent(post *Post) (string, error) {
+	// Expand shortcodes before the markdown is parsed
+	content, shortcodes, err := g.expandShortcodes(post.Content, "posts/"+post.Filename, post.BodyLine)
+	if err != nil {
+		return "", err
+	}
+
 	// Convert markdown content to HTML
-	htmlContent, err := g.markdownToHTML(post.Content)
+	htmlContent, err := g.markdownToHTML(content)
 	if err != nil {
-		return "", fmt.Errorf("failed to convert markdown to HTML: %w", err)
+		return "", fmt.Errorf("failed to convert posts/%s to HTML: %w", post.Filename, err)
+	}
+	return restoreShortcodes(htmlContent, shortcodes), nil
+}
+
+func (g *Generator) generatePostHTML(post *Post) (string, error) {
+	htmlContent, err := g.renderPostContent(post)
+	if err != nil {
+		return "", err
 	}
 
 	// Parse tags
@@ -199,7 +240,9 @@ func (g *Generator) generatePostHTML(post *Post) (string, error) {
 	var tagsHTML strings.Builder
 	for _, tag := range tags {
 		tag = strings.TrimSpace(tag)
-		if tag != "" {
+		if g.slugify(tag) != "" {
+			tagsHTML.WriteString(fmt.Sprintf(`<a href="%s" class="post-tag">%s</a>`, g.tagURL(tag), template.HTMLEscapeString(tag)))
+		} else if tag != "" {
 			tagsHTML.WriteString(fmt.Sprintf(`<span class="post-tag">%s</span>`, tag))
 		}
 	}
@@ -212,17 +255,14 @@ func (g *Generator) generatePostHTML(post *Post) (string, error) {
   <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
   <meta name="description" content="{{.Description}}">
   <meta name="keywords" content="{{.Tags}}">
-  <meta property="og:title" content="{{.Title}} - Jordan Joe Cooper">
-  <meta property="og:type" content="article">
-  <link rel="apple-touch-icon" sizes="180x180" href="../images/apple-touch-icon.png">
-  <link rel="icon" type="image/png" sizes="32x32" href="../images/favicon-32x32.png">
-  <link rel="icon" type="image/png" sizes="16x16" href="../images/favicon-16x16.png">
-  <link rel="manifest" href="../site.webmanifest">
-  <link rel="preconnect" href="https://fonts.googleapis.com">
-  <link rel="preconnect" href="https://fonts.gstatic.com" crossorigin>
-  <link href="https://fonts.googleapis.com/css2?family=Fraunces:opsz,wght@9..144,400;9..144,500;9..144,600&family=Inter:wght@400;500&display=swap" rel="stylesheet">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
   <title>{{.Title}} - Jordan Joe Cooper</title>
-  <link rel="stylesheet" href="../styles.css">
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
 </head>
 <body>
   <nav class="navbar">
@@ -230,23 +270,45 @@ func (g *Generator) generatePostHTML(post *Post) (string, error) {
       <a href="/" class="logo">J</a>
       <div class="nav-links">
         <a href="/index.html#notes">Writing</a>
-        <a href="../about.html">About</a>
+        <a href="/about.html">About</a>
       </div>
     </div>
   </nav>
   <div class="container">
+    {{- with .Link}}
+    <header class="post-heading link-heading">
+      <h1><a href="{{.URL}}">{{$.Title}}</a></h1>
+      <p class="link-source">{{.Host}}{{with .Via}} · via {{if .URL}}<a href="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{end}}</p>
+      <time>{{$.Created}}</time>
+    </header>
+    {{- else}}
     <header class="post-heading">
       <h1>{{.Title}}</h1>
       <p class="post-description">{{.Description}}</p>
       <time>{{.Created}}</time>
+      <span class="reading-time">{{.ReadingTime}} min read</span>
     </header>
+    {{- end}}
     <main>
+      {{- with .Link}}{{with .Quote}}
+      <blockquote class="link-quote">{{.}}</blockquote>
+      {{- end}}{{end}}
+      {{- with .Series}}
+      <aside class="series-box">
+        <p>Part {{.Part}} of {{.Total}} in <a href="{{.URL}}">{{.Title}}</a></p>
+        <ol>
+          {{- range .Parts}}
+          <li value="{{.Part}}">{{if .Current}}<strong>{{.Title}}</strong>{{else}}<a href="{{.URL}}">{{.Title}}</a>{{end}}</li>
+          {{- end}}
+        </ol>
+      </aside>
+      {{- end}}
       <div class="post-content">
         {{.HTMLContent}}
       </div>
       <footer class="post-footer">
         <div class="post-metadata-footer">
-          <span>{{.Section}}</span>
+          <span>{{if .SectionURL}}<a href="{{.SectionURL}}">{{.Section}}</a>{{else}}{{.Section}}{{end}}</span>
           <span>•</span>
           <span>Jordan Joe Cooper</span>
         </div>
@@ -257,27 +319,86 @@ func (g *Generator) generatePostHTML(post *Post) (string, error) {
           Last updated: <time>{{.Updated}}</time>
         </div>
       </footer>
+      {{- if or .Previous .Next}}
+      <nav class="post-nav" aria-label="More in {{.Section}}">
+        {{- with .Previous}}
+        <a href="{{.URL}}" class="post-nav-previous" rel="prev"><span>Previous</span>{{.Title}}</a>
+        {{- end}}
+        {{- with .Next}}
+        <a href="{{.URL}}" class="post-nav-next" rel="next"><span>Next</span>{{.Title}}</a>
+        {{- end}}
+      </nav>
+      {{- end}}
+      {{- if .Related}}
+      <section class="related-posts">
+        <h2>Related</h2>
+        <ul>
+          {{- range .Related}}
+          <li><a href="{{.URL}}">{{.Title}}</a><p>{{.Description}}</p></li>
+          {{- end}}
+        </ul>
+      </section>
+      {{- end}}
+      {{- if .Backlinks}}
+      <section class="backlinks">
+        <h2>Linked from</h2>
+        <ul>
+          {{- range .Backlinks}}
+          <li><a href="{{.URL}}">{{.Title}}</a></li>
+          {{- end}}
+        </ul>
+      </section>
+      {{- end}}
       <div class="back-button-container">
-        <a href="../" class="back-button">Back to home</a>
+        <a href="/" class="back-button">Back to home</a>
       </div>
     </main>
   </div>
 </body>
 </html>`
 
-	t, err := template.New("post").Parse(tmpl)
+	t, err := template.New("post").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
 
+	meta, err := g.postMeta(post)
+	if err != nil {
+		return "", err
+	}
+
+	var sectionURL string
+	if g.slugify(post.Section) != "" {
+		sectionURL = g.sectionURL(post.Section)
+	}
+
+	var related []*postLink
+	for _, relatedPost := range post.Related {
+		related = append(related, g.linkTo(relatedPost))
+	}
+
 	var buf strings.Builder
 	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":        meta,
 		"Title":       post.Title,
 		"Description": post.Description,
 		"Section":     post.Section,
+		"SectionURL":  sectionURL,
 		"Tags":        post.Tags,
 		"Created":     post.Created,
 		"Updated":     post.Updated,
+		"WordCount":   post.WordCount,
+		"ReadingTime": post.ReadingTime,
+		"Excerpt":     post.Excerpt,
+		"Previous":    g.linkTo(post.Previous),
+		"Next":        g.linkTo(post.Next),
+		"Related":     related,
+		"Series":      g.seriesBoxFor(post),
+		"Backlinks":   post.Backlinks,
+		"Link":        g.linkInfo(post),
 		"HTMLContent": template.HTML(htmlContent),
 		"TagsHTML":    template.HTML(tagsHTML.String()),
 	})
@@ -288,12 +409,11 @@ func (g *Generator) generatePostHTML(post *Post) (string, error) {
 	return buf.String(), nil
 }
 
-func (g *Generator) UpdateHomepage() error {
-	// Read all markdown posts
+func (g *Generator) loadPosts() ([]*Post, error) {
 	postsDir := filepath.Join(g.rootDir, "posts")
 	posts := []*Post{}
 
-	if err := filepath.Walk(postsDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(postsDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -321,46 +441,104 @@ func (g *Generator) UpdateHomepage() error {
 				Created:     metadata["created"],
 				Updated:     metadata["updated"],
 				Type:        metadata["type"],
+				Cover:       metadata["cover"],
+				Series:      metadata["series"],
 				Content:     body,
 				Slug:        strings.TrimSuffix(filepath.Base(path), ".md"),
 				Filename:    filepath.Base(path),
+				BodyLine:    bodyLine(string(content), body),
+			}
+			post.SeriesOrder, _ = strconv.Atoi(metadata["series_order"])
+			post.Aliases = splitTags(metadata["aliases"])
+			if post.Type == "link" {
+				post.LinkURL = metadata["url"]
+				post.Via = metadata["via"]
+				post.Quote = metadata["quote"]
 			}
 			posts = append(posts, post)
 		}
 		return nil
-	}); err != nil {
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (g *Generator) UpdateHomepage() error {
+	// Every page links fonts.css, so a missing font fails before anything is written
+	if _, err := g.loadAssets(); err != nil {
+		return err
+	}
+
+	// Read all markdown posts
+	posts, err := g.loadPosts()
+	if err != nil {
 		return fmt.Errorf("failed to read posts: %w", err)
 	}
 
+	// Resolve [[slug]] links against posts and library items
+	items, err := g.loadLibraryItems()
+	if err != nil {
+		return fmt.Errorf("failed to read library: %w", err)
+	}
+	if err := g.resolveLinks(posts, items); err != nil {
+		return fmt.Errorf("failed to resolve links:\n%w", err)
+	}
+
+	// Pages link to the feeds they appear in
+	g.feeds = make(map[string]*postFeed)
+	for _, feed := range g.collectFeeds(posts) {
+		g.feeds[feed.Dir] = feed
+	}
+
 	// Generate HTML files from markdown posts
 	if err := g.generatePostHTMLFiles(posts); err != nil {
 		return fmt.Errorf("failed to generate HTML files: %w", err)
 	}
 
-	// Sort posts by date (newest first)
-	// TODO: Implement proper date sorting
+	// Keep the old URLs of renamed posts working
+	if err := g.generateRedirects(posts, items); err != nil {
+		return fmt.Errorf("failed to generate redirects:\n%w", err)
+	}
 
 	// Generate homepage HTML
 	homepagePath := filepath.Join(g.rootDir, "index.html")
-	homepageContent, err := g.generateHomepageHTML(posts)
+	homepageContent, err := g.generateHomepageHTML(posts, items)
 	if err != nil {
 		return fmt.Errorf("failed to generate homepage: %w", err)
 	}
 
-	if err := os.WriteFile(homepagePath, []byte(homepageContent), 0644); err != nil {
+	if err := g.writePage(homepagePath, homepageContent); err != nil {
 		return fmt.Errorf("failed to write homepage: %w", err)
 	}
 
+	if err := g.generateFeeds(posts); err != nil {
+		return fmt.Errorf("failed to generate feeds: %w", err)
+	}
+
+	if err := g.generateListPages(posts); err != nil {
+		return fmt.Errorf("failed to generate list pages: %w", err)
+	}
+
+	if err := g.generateArchivePages(posts); err != nil {
+		return fmt.Errorf("failed to generate archive: %w", err)
+	}
+
+	if err := g.generateAPI(posts, items); err != nil {
+		return fmt.Errorf("failed to generate JSON API: %w", err)
+	}
+
 	fmt.Printf("Homepage updated with %d posts\n", len(posts))
-	return nil
+
+	return g.WriteHeaders()
 }
 
 func (g *Generator) generatePostHTMLFiles(posts []*Post) error {
-	// Create posts HTML directory if it doesn't exist
-	postsHTMLDir := filepath.Join(g.rootDir, "posts")
-	if err := os.MkdirAll(postsHTMLDir, 0755); err != nil {
-		return fmt.Errorf("failed to create posts HTML directory: %w", err)
-	}
+	// Navigation, series and related posts need the full post set
+	g.linkPosts(posts)
+	allSeries := g.collectSeries(posts)
 
 	// Generate HTML for each post
 	for _, post := range posts {
@@ -369,85 +547,74 @@ func (g *Generator) generatePostHTMLFiles(posts []*Post) error {
 			return fmt.Errorf("failed to generate HTML for post %s: %w", post.Slug, err)
 		}
 
-		htmlPath := filepath.Join(postsHTMLDir, post.Slug+".html")
-		if err := os.WriteFile(htmlPath, []byte(htmlContent), 0644); err != nil {
+		htmlPath := g.outputPath(g.postURL(post))
+		if err := g.writePage(htmlPath, htmlContent); err != nil {
 			return fmt.Errorf("failed to write HTML file for post %s: %w", post.Slug, err)
 		}
+
+		if post.Cover == "" {
+			if err := g.generatePreviewImage(post); err != nil {
+				return fmt.Errorf("failed to generate preview image for post %s: %w", post.Slug, err)
+			}
+		}
+	}
+
+	if err := g.generateSeriesPages(allSeries); err != nil {
+		return err
+	}
+
+	if err := g.generateLinksPage(posts); err != nil {
+		return err
 	}
 
 	fmt.Printf("Generated HTML files for %d posts\n", len(posts))
 	return nil
 }
 
-func (g *Generator) generateHomepageHTML(posts []*Post) (string, error) {
-	// Read the existing homepage template
-	templatePath := filepath.Join(g.rootDir, "index.html")
+// homepageTemplate is the hand-written page index.html is generated from.
+const homepageTemplate = "templates/index.html"
+
+func (g *Generator) generateHomepageHTML(posts []*Post, items []*LibraryItem) (string, error) {
+	// The homepage is written by hand in templates/index.html, with markers
+	// around the blocks filled in here
+	templatePath := filepath.Join(g.rootDir, homepageTemplate)
 	templateContent, err := os.ReadFile(templatePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read homepage template: %w", err)
 	}
 
-	// Generate notes section HTML
-	var notesHTML strings.Builder
-	for _, post := range posts {
-		if post.Section == "Notes" {
-			date := post.Created
-			if date == "" {
-				date = "Unknown date"
-			}
+	// Replace the notes section with the first page of the writing list
+	content, err := g.replaceHomepageNotes(string(templateContent), posts)
+	if err != nil {
+		return "", err
+	}
 
-			notesHTML.WriteString(fmt.Sprintf(`<a href="posts/%s.html" class="note-row">
-      <div class="note-header">
-        <time>%s</time>
-        <h3>%s</h3>
-      </div>
-      <p>%s</p>
-    </a>`, post.Slug, date, post.Title, post.Description))
-		}
+	content, err = g.replaceHomepageArchive(content, posts)
+	if err != nil {
+		return "", err
+	}
+
+	// The homepage lists Notes, so it advertises that feed with the site's
+	if start, end := strings.Index(content, homepageFeedsStart), strings.Index(content, homepageFeedsEnd); start >= 0 && end > start {
+		links := feedLinksHTML(g.pageFeeds(g.writingList(posts).Section, nil))
+		content = content[:start+len(homepageFeedsStart)] + links + "\n  " + content[end:]
 	}
 
-	// Replace the notes section in the template
-	content := string(templateContent)
-	content = strings.Replace(content,
-		`<!-- Notes will be dynamically inserted here -->`,
-		notesHTML.String(),
-		1)
+	content, err = g.replaceHomepageLibrary(content, items)
+	if err != nil {
+		return "", err
+	}
 
-	return content, nil
+	// The template links assets by their plain names
+	return g.rewriteAssetRefs(content)
 }
 
 func (g *Generator) UpdateSitemap() error {
-	baseURL := "https://jordanjoecooper.dev"
-	posts := []*Post{}
+	baseURL := g.config.BaseURL
 
 	// Read all markdown posts
-	postsDir := filepath.Join(g.rootDir, "posts")
-	if err := filepath.Walk(postsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(path, ".md") {
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-
-			metadata, _, err := g.parseMarkdownFrontmatter(string(content))
-			if err != nil {
-				return err
-			}
-
-			post := &Post{
-				Title:       metadata["title"],
-				Description: metadata["description"],
-				Created:     metadata["created"],
-				Updated:     metadata["updated"],
-				Slug:        strings.TrimSuffix(filepath.Base(path), ".md"),
-			}
-			posts = append(posts, post)
-		}
-		return nil
-	}); err != nil {
+	posts, err := g.loadPosts()
+	if err != nil {
 		return fmt.Errorf("failed to read posts: %w", err)
 	}
 
@@ -467,6 +634,39 @@ func (g *Generator) UpdateSitemap() error {
     <priority>0.8</priority>
   </url>`, baseURL, time.Now().Format("2006-01-02"), baseURL, time.Now().Format("2006-01-02"))
 
+	// Add the library index and reading stats
+	for _, url := range []string{libraryIndexURL, g.readingURL()} {
+		sitemap += fmt.Sprintf(`
+  <url>
+    <loc>%s</loc>
+    <lastmod>%s</lastmod>
+    <changefreq>monthly</changefreq>
+    <priority>0.7</priority>
+  </url>`, g.absURL(url), time.Now().Format("2006-01-02"))
+	}
+
+	// Add the links stream if there are any links
+	if len(linkPosts(posts)) > 0 {
+		sitemap += fmt.Sprintf(`
+  <url>
+    <loc>%s</loc>
+    <lastmod>%s</lastmod>
+    <changefreq>weekly</changefreq>
+    <priority>0.7</priority>
+  </url>`, g.absURL(g.linksURL()), time.Now().Format("2006-01-02"))
+	}
+
+	// Add the writing list's later pages, the section and tag pages and the archive
+	for _, url := range append(g.listURLs(posts), g.archiveURLs(posts)...) {
+		sitemap += fmt.Sprintf(`
+  <url>
+    <loc>%s</loc>
+    <lastmod>%s</lastmod>
+    <changefreq>weekly</changefreq>
+    <priority>0.5</priority>
+  </url>`, g.absURL(url), time.Now().Format("2006-01-02"))
+	}
+
 	// Add posts to sitemap
 	for _, post := range posts {
 		lastmod := post.Updated
@@ -479,11 +679,11 @@ func (g *Generator) UpdateSitemap() error {
 
 		sitemap += fmt.Sprintf(`
   <url>
-    <loc>%s/posts/%s.html</loc>
+    <loc>%s</loc>
     <lastmod>%s</lastmod>
     <changefreq>monthly</changefreq>
     <priority>0.6</priority>
-  </url>`, baseURL, post.Slug, lastmod)
+  </url>`, g.absURL(g.postURL(post)), lastmod)
 	}
 
 	sitemap += `
@@ -499,11 +699,6 @@ func (g *Generator) UpdateSitemap() error {
 	return nil
 }
 
-func (g *Generator) UpdateLibrary() error {
-	// TODO: Implement library update
-	return nil
-}
-
 func (g *Generator) ConvertToMarkdown() error {
 	postsDir := filepath.Join(g.rootDir, "posts")
 
@@ -544,7 +739,7 @@ func (g *Generator) convertHTMLPostToMarkdown(htmlPath, markdownDir string) erro
 	// Extract content from HTML
 	contentHTML := g.extractHTMLContent(string(content))
 
-	// Convert HTML content to markdown (simplified)
+	// Convert HTML content to markdown
 	markdownContent := g.htmlToMarkdown(contentHTML)
 
 	// Generate markdown with frontmatter
@@ -581,6 +776,8 @@ func (g *Generator) extractHTMLMetadata(content string) map[string]string {
 	patterns := map[string]string{
 		"title":       `<!--\s*Title:\s*(.*?)\s*-->`,
 		"description": `<!--\s*Description:\s*(.*?)\s*-->`,
+		"author":      `<!--\s*Author:\s*(.*?)\s*-->`,
+		"year":        `<!--\s*Year:\s*(.*?)\s*-->`,
 		"section":     `<!--\s*Section:\s*(.*?)\s*-->`,
 		"tags":        `<!--\s*Tags:\s*(.*?)\s*-->`,
 		"created":     `<!--\s*Created:\s*(.*?)\s*-->`,
@@ -616,33 +813,6 @@ func (g *Generator) extractHTMLContent(content string) string {
 }
 
 func (g *Generator) htmlToMarkdown(html string) string {
-	// Simple HTML to markdown conversion
-	// This is a basic implementation - for production, consider using a proper library
-
-	// Remove HTML tags but preserve line breaks
-	html = strings.ReplaceAll(html, "<p>", "")
-	html = strings.ReplaceAll(html, "</p>", "\n\n")
-	html = strings.ReplaceAll(html, "<br>", "\n")
-	html = strings.ReplaceAll(html, "<br/>", "\n")
-	html = strings.ReplaceAll(html, "<br />", "\n")
-
-	// Convert headings
-	html = regexp.MustCompile(`<h1[^>]*>(.*?)</h1>`).ReplaceAllString(html, "# $1")
-	html = regexp.MustCompile(`<h2[^>]*>(.*?)</h2>`).ReplaceAllString(html, "## $1")
-	html = regexp.MustCompile(`<h3[^>]*>(.*?)</h3>`).ReplaceAllString(html, "### $1")
-
-	// Convert links
-	html = regexp.MustCompile(`<a[^>]*href="([^"]*)"[^>]*>(.*?)</a>`).ReplaceAllString(html, "[$2]($1)")
-
-	// Convert bold and italic
-	html = regexp.MustCompile(`<strong[^>]*>(.*?)</strong>`).ReplaceAllString(html, "**$1**")
-	html = regexp.MustCompile(`<em[^>]*>(.*?)</em>`).ReplaceAllString(html, "*$1*")
-
-	// Remove remaining HTML tags
-	html = regexp.MustCompile(`<[^>]*>`).ReplaceAllString(html, "")
-
-	// Clean up whitespace
-	html = strings.TrimSpace(html)
-
-	return html
+	converter := &htmlConverter{}
+	return converter.convert(html)
 }
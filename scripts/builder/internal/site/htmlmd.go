@@ -0,0 +1,440 @@
+package site
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlNode is an element or text in the tree htmlToMarkdown converts.
+type htmlNode struct {
+	tag      string
+	attrs    map[string]string
+	text     string
+	raw      string
+	children []*htmlNode
+	parent   *htmlNode
+}
+
+var (
+	htmlTokenPattern  = regexp.MustCompile(`(?s)<!--.*?-->|<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	htmlAttrsPattern  = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	codeLangPattern   = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([A-Za-z0-9_+-]+)`)
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+	paragraphPattern  = regexp.MustCompile(`[ \t]*\n[ \t]*\n\s*`)
+
+	// markdownTextPattern matches what markdown would read as syntax in
+	// text: emphasis, links, code, math, shortcodes, HTML tags and entities
+	markdownTextPattern = regexp.MustCompile("[\\\\`*_\\[\\]~$]|\\{\\{|<[A-Za-z/!?]|&#?[A-Za-z0-9]+;")
+
+	// lineStartPattern matches what would start a heading, quote, list or
+	// setext underline at the start of a line
+	lineStartPattern = regexp.MustCompile(`(?m)^[ \t]*(?:[#>+=-]|\d+[.)](?:\s|$))`)
+)
+
+// voidElements never have children or closing tags.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// markdownBlocks start a new markdown block.
+var markdownBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "ul": true, "iframe": true, "video": true, "audio": true,
+}
+
+// rawElements have no markdown equivalent and are kept as HTML.
+var rawElements = map[string]bool{
+	"table": true, "iframe": true, "video": true, "audio": true, "details": true, "dl": true,
+	"sup": true, "sub": true,
+}
+
+// droppedElements are removed along with their content.
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "form": true, "button": true,
+}
+
+// htmlConverter turns HTML into markdown. Anything it can't express, such as
+// tables and embeds, is kept as raw HTML.
+type htmlConverter struct {
+	// paragraphs treats blank lines in text as paragraph breaks, as
+	// WordPress content does
+	paragraphs bool
+
+	// warnings collects what was dropped during conversion
+	warnings []string
+}
+
+func (c *htmlConverter) convert(source string) string {
+	root := parseHTMLTree(source)
+	markdown := c.blocks(root.children)
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(markdown, "\n\n"))
+}
+
+// parseHTMLTree builds a forgiving tree from HTML: stray closing tags are
+// ignored and unclosed elements end with their parent.
+func parseHTMLTree(source string) *htmlNode {
+	root := &htmlNode{}
+	current := root
+	last := 0
+
+	addText := func(text string) {
+		if text != "" {
+			current.children = append(current.children, &htmlNode{text: text, parent: current})
+		}
+	}
+
+	for _, loc := range htmlTokenPattern.FindAllStringSubmatchIndex(source, -1) {
+		addText(source[last:loc[0]])
+		last = loc[1]
+		if loc[4] < 0 {
+			// Comments, including WordPress block markers, are dropped
+			continue
+		}
+
+		closing := loc[3] > loc[2]
+		tag := strings.ToLower(source[loc[4]:loc[5]])
+		if closing {
+			for n := current; n != root; n = n.parent {
+				if n.tag == tag {
+					current = n.parent
+					break
+				}
+			}
+			continue
+		}
+
+		// A new block closes an open paragraph, and a new item an open item
+		if markdownBlocks[tag] && current.tag == "p" {
+			current = current.parent
+		}
+		if tag == "li" || tag == "dt" || tag == "dd" {
+			for n := current; n != root && n.tag != "ul" && n.tag != "ol" && n.tag != "dl"; n = n.parent {
+				if n.tag == "li" || n.tag == "dt" || n.tag == "dd" {
+					current = n.parent
+					break
+				}
+			}
+		}
+
+		node := &htmlNode{tag: tag, attrs: parseHTMLAttrs(source[loc[6]:loc[7]]), raw: source[loc[0]:loc[1]], parent: current}
+		current.children = append(current.children, node)
+		if !voidElements[tag] && !strings.HasSuffix(strings.TrimSpace(source[loc[6]:loc[7]]), "/") {
+			current = node
+		}
+	}
+	addText(source[last:])
+	return root
+}
+
+func parseHTMLAttrs(source string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range htmlAttrsPattern.FindAllStringSubmatch(source, -1) {
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attrs
+}
+
+// blocks renders nodes as markdown blocks, gathering inline content into
+// paragraphs.
+func (c *htmlConverter) blocks(nodes []*htmlNode) string {
+	var out []string
+	var inline []*htmlNode
+
+	flush := func() {
+		text := c.inline(inline)
+		inline = nil
+		if c.paragraphs {
+			for _, paragraph := range paragraphPattern.Split(text, -1) {
+				if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+					out = append(out, escapeLineStarts(paragraph))
+				}
+			}
+		} else if text = strings.TrimSpace(text); text != "" {
+			out = append(out, escapeLineStarts(text))
+		}
+	}
+
+	for _, node := range nodes {
+		if node.tag == "" || !markdownBlocks[node.tag] {
+			inline = append(inline, node)
+			continue
+		}
+		flush()
+		if block := strings.TrimSpace(c.block(node)); block != "" {
+			out = append(out, block)
+		}
+	}
+	flush()
+	return strings.Join(out, "\n\n")
+}
+
+func (c *htmlConverter) block(node *htmlNode) string {
+	switch node.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(node.tag[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(c.inline(node.children))
+	case "hr":
+		return "---"
+	case "pre":
+		return c.codeBlock(node)
+	case "blockquote":
+		return prefixLines(c.blocks(node.children), "> ")
+	case "ul", "ol":
+		return c.list(node)
+	case "figure":
+		return c.figure(node)
+	}
+	if rawElements[node.tag] {
+		return outerHTML(node)
+	}
+	return c.blocks(node.children)
+}
+
+func (c *htmlConverter) codeBlock(node *htmlNode) string {
+	lang := ""
+	if match := codeLangPattern.FindStringSubmatch(node.attrs["class"]); match != nil {
+		lang = match[1]
+	}
+	for _, child := range node.children {
+		if child.tag == "code" {
+			if match := codeLangPattern.FindStringSubmatch(child.attrs["class"]); match != nil {
+				lang = match[1]
+			}
+		}
+	}
+	code := strings.Trim(html.UnescapeString(textContent(node)), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func (c *htmlConverter) list(node *htmlNode) string {
+	var items []string
+	n := 1
+	if start := node.attrs["start"]; start != "" {
+		fmt.Sscanf(start, "%d", &n)
+	}
+	for _, child := range node.children {
+		if child.tag != "li" {
+			continue
+		}
+		body := c.blocks(child.children)
+		if body == "" {
+			continue
+		}
+		marker := "- "
+		if node.tag == "ol" {
+			marker = fmt.Sprintf("%d. ", n)
+			n++
+		}
+		// Continuation lines line up with the text after the marker
+		body = prefixLines(body, strings.Repeat(" ", len(marker)))
+		items = append(items, marker+strings.TrimLeft(body, " "))
+	}
+	return strings.Join(items, "\n")
+}
+
+// figure becomes the figure shortcode when it's an image with a caption.
+func (c *htmlConverter) figure(node *htmlNode) string {
+	var img *htmlNode
+	caption, text := "", ""
+	var walk func(n *htmlNode)
+	walk = func(n *htmlNode) {
+		for _, child := range n.children {
+			switch child.tag {
+			case "img":
+				if img == nil {
+					img = child
+				}
+			case "figcaption":
+				// The shortcode shows captions as plain text
+				caption = strings.TrimSpace(collapseWhitespace(html.UnescapeString(textContent(child))))
+			case "":
+				text += child.text
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+
+	// Figures with more than an image, such as link cards, keep their content
+	if img == nil || strings.TrimSpace(text) != "" {
+		return c.blocks(node.children)
+	}
+	if caption == "" {
+		return c.image(img)
+	}
+	return fmt.Sprintf(`{{< figure src=%q alt=%q caption=%q >}}`, img.attrs["src"], img.attrs["alt"], caption)
+}
+
+func (c *htmlConverter) image(node *htmlNode) string {
+	src := node.attrs["src"]
+	if src == "" {
+		return ""
+	}
+	alt := strings.NewReplacer("[", "", "]", "").Replace(node.attrs["alt"])
+	if title := node.attrs["title"]; title != "" {
+		return fmt.Sprintf(`![%s](%s %q)`, alt, src, title)
+	}
+	return fmt.Sprintf("![%s](%s)", alt, src)
+}
+
+// inline renders nodes as a run of inline markdown.
+func (c *htmlConverter) inline(nodes []*htmlNode) string {
+	var out strings.Builder
+	for _, node := range nodes {
+		if node.tag == "" {
+			text := html.UnescapeString(node.text)
+			if c.paragraphs {
+				// Keep blank lines so blocks can split paragraphs on them
+				parts := paragraphPattern.Split(text, -1)
+				for i, part := range parts {
+					parts[i] = collapseWhitespace(part)
+				}
+				text = strings.Join(parts, "\n\n")
+			} else {
+				text = collapseWhitespace(text)
+			}
+			out.WriteString(escapeMarkdownText(text))
+			continue
+		}
+
+		switch node.tag {
+		case "br":
+			out.WriteString("  \n")
+		case "strong", "b":
+			out.WriteString(wrapInline(c.inline(node.children), "**"))
+		case "em", "i":
+			out.WriteString(wrapInline(c.inline(node.children), "*"))
+		case "del", "s", "strike":
+			out.WriteString(wrapInline(c.inline(node.children), "~~"))
+		case "code":
+			code := html.UnescapeString(textContent(node))
+			tick := "`"
+			for strings.Contains(code, tick) {
+				tick += "`"
+			}
+			out.WriteString(tick + code + tick)
+		case "a":
+			text := strings.TrimSpace(c.inline(node.children))
+			href := node.attrs["href"]
+			switch {
+			case href == "":
+				out.WriteString(text)
+			case text == "":
+			case strings.TrimSpace(html.UnescapeString(textContent(node))) == href:
+				out.WriteString("<" + href + ">")
+			default:
+				out.WriteString("[" + text + "](" + href + ")")
+			}
+		case "img":
+			out.WriteString(c.image(node))
+		default:
+			if droppedElements[node.tag] {
+				c.warn(fmt.Sprintf("dropped <%s>", node.tag))
+				continue
+			}
+			if rawElements[node.tag] {
+				out.WriteString(outerHTML(node))
+				continue
+			}
+			out.WriteString(c.inline(node.children))
+		}
+	}
+	return out.String()
+}
+
+// escapeMarkdownText backslash-escapes text so markdown shows it as it is.
+func escapeMarkdownText(text string) string {
+	return markdownTextPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if match == "{{" {
+			return `\{\{`
+		}
+		return `\` + match
+	})
+}
+
+// escapeLineStarts escapes the markers that would turn a line of a
+// paragraph into another block, such as "1. " or "# ".
+func escapeLineStarts(text string) string {
+	return lineStartPattern.ReplaceAllStringFunc(text, func(match string) string {
+		marker := strings.TrimLeft(match, " \t")
+		indent := match[:len(match)-len(marker)]
+		if i := strings.IndexAny(marker, ".)"); i > 0 {
+			return indent + marker[:i] + `\` + marker[i:]
+		}
+		return indent + `\` + marker
+	})
+}
+
+func (c *htmlConverter) warn(warning string) {
+	for _, existing := range c.warnings {
+		if existing == warning {
+			return
+		}
+	}
+	c.warnings = append(c.warnings, warning)
+}
+
+// wrapInline puts markers around text, keeping surrounding spaces outside
+// them so the markdown still parses.
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := text[:strings.Index(text, trimmed)]
+	end := text[len(start)+len(trimmed):]
+	return start + marker + trimmed + marker + end
+}
+
+func textContent(node *htmlNode) string {
+	if node.tag == "" {
+		return node.text
+	}
+	var text strings.Builder
+	for _, child := range node.children {
+		if child.tag == "br" {
+			text.WriteString("\n")
+		}
+		text.WriteString(textContent(child))
+	}
+	return text.String()
+}
+
+// outerHTML writes an element back out as HTML.
+func outerHTML(node *htmlNode) string {
+	if node.tag == "" {
+		return node.text
+	}
+	var out strings.Builder
+	out.WriteString(node.raw)
+	for _, child := range node.children {
+		out.WriteString(outerHTML(child))
+	}
+	if !voidElements[node.tag] {
+		out.WriteString("</" + node.tag + ">")
+	}
+	return out.String()
+}
+
+func prefixLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,46 @@
+package site
+
+import "testing"
+
+func TestHTMLConverterEscapesText(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"entities", `<p>Use &lt;script&gt; &amp;amp; &amp;lt;b&gt;</p>`, `Use \<script> \&amp; \&lt;b>`},
+		{"ampersand", `<p>Tom &amp; Jerry &lt; 3</p>`, `Tom & Jerry < 3`},
+		{"ordered list", `<p>1. not a list</p>`, `1\. not a list`},
+		{"heading", `<p># x</p>`, `\# x`},
+		{"quote", `<p>&gt; not quoted</p>`, `\> not quoted`},
+		{"bullet", `<p>- not a list<br>+ nor this</p>`, "\\- not a list  \n\\+ nor this"},
+		{"decimal", `<p>3.14 is pi</p>`, `3.14 is pi`},
+		{"emphasis", `<p>2*3*4 and snake_case_name</p>`, `2\*3\*4 and snake\_case\_name`},
+		{"link text", `<p><a href="/x">a *starred* [word]</a></p>`, `[a \*starred\* \[word\]](/x)`},
+		{"code", "<p>`tick` and <code>a*b</code></p>", "\\`tick\\` and `a*b`"},
+		{"math", `<p>$x$ and \n</p>`, `\$x\$ and \\n`},
+		{"shortcode", `<p>{{&lt; figure &gt;}}</p>`, `\{\{< figure >}}`},
+		{"list item", `<ol><li>1. twice</li></ol>`, `1. 1\. twice`},
+		{"blockquote", `<blockquote><p># x</p></blockquote>`, `> \# x`},
+		{"autolink", `<p><a href="https://example.com/a_b">https://example.com/a_b</a></p>`, `<https://example.com/a_b>`},
+	}
+	for _, test := range tests {
+		c := &htmlConverter{}
+		if got := c.convert(test.html); got != test.want {
+			t.Errorf("%s: convert(%q) = %q, want %q", test.name, test.html, got, test.want)
+		}
+	}
+}
+
+func TestHTMLConverterRoundTrip(t *testing.T) {
+	g := NewGenerator()
+	g.config.Markdown.Math = true
+	source := `<p>1. &lt;script&gt;alert(1)&lt;/script&gt; costs $5 &amp; $x$ *more*</p><p># [not a link](x) <em>but this</em></p>`
+	want := "<p>1. &lt;script&gt;alert(1)&lt;/script&gt; costs $5 &amp; $x$ *more*</p>\n\n<p># [not a link](x) <em>but this</em></p>\n"
+
+	got, err := g.markdownToHTML((&htmlConverter{}).convert(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("round trip = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,427 @@
+package site
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImportOptions controls an import of posts from another blogging tool.
+type ImportOptions struct {
+	// From names the importer, and is detected from File when empty
+	From string
+	File string
+
+	// Media is a copy of the old site's uploads or static directory that
+	// images are looked for in
+	Media string
+
+	// Section is given to imported posts, defaulting to Notes
+	Section string
+	Force   bool
+}
+
+// importedPost is a post read from an export, before it's written out.
+type importedPost struct {
+	Title       string
+	Slug        string
+	Description string
+	Tags        []string
+	Created     time.Time
+	Updated     time.Time
+	Draft       bool
+	Cover       string
+
+	// Body is markdown; importers convert HTML bodies with htmlConverter
+	Body string
+
+	// Source names the post in the report, as its old URL or file
+	Source string
+
+	// Dir is where relative image paths are resolved, for page bundles
+	Dir string
+
+	Warnings []string
+}
+
+// importSource is everything an importer read from an export.
+type importSource struct {
+	Posts []*importedPost
+
+	// MediaDirs are searched for images after ImportOptions.Media
+	MediaDirs []string
+
+	// Skipped describes entries that aren't posts, such as pages
+	Skipped []string
+}
+
+// postImporter reads one tool's export.
+type postImporter interface {
+	// detect reports whether path looks like this importer's export.
+	detect(path string) bool
+	read(path string) (*importSource, error)
+}
+
+// postImporters are the formats -from accepts.
+var postImporters = map[string]postImporter{
+	"wordpress": wordpressImporter{},
+	"ghost":     ghostImporter{},
+	"hugo":      markdownImporter{jekyll: false},
+	"jekyll":    markdownImporter{jekyll: true},
+}
+
+// importerOrder is the order formats are tried in when -from isn't given.
+var importerOrder = []string{"wordpress", "ghost", "jekyll", "hugo"}
+
+// importReportFile lists what an import did, post by post.
+const importReportFile = "import-report.md"
+
+var (
+	markdownImagePattern = regexp.MustCompile(`(!\[[^\]]*\]\()(<[^>]*>|[^)\s]+)`)
+	srcAttrPattern       = regexp.MustCompile(`(\bsrc=)("[^"]*"|'[^']*')`)
+	shortcodeNamePattern = regexp.MustCompile(`\{\{([<%])\s*([\w-]+)`)
+)
+
+// ImportPosts creates a post for each post in another tool's export.
+// Posts whose slug is already taken are skipped unless opts.Force is set, so
+// an export can be imported again after fixing what the report lists.
+func (g *Generator) ImportPosts(opts ImportOptions) error {
+	from := opts.From
+	if from == "" {
+		for _, name := range importerOrder {
+			if postImporters[name].detect(opts.File) {
+				from = name
+				break
+			}
+		}
+		if from == "" {
+			return fmt.Errorf("can't tell what %s was exported from, pass -from wordpress, ghost, hugo or jekyll", opts.File)
+		}
+	}
+	importer, ok := postImporters[from]
+	if !ok {
+		return fmt.Errorf("unknown import format %q (use wordpress, ghost, hugo or jekyll)", from)
+	}
+
+	source, err := importer.read(opts.File)
+	if err != nil {
+		return fmt.Errorf("failed to read %s export: %w", from, err)
+	}
+	mediaDirs := source.MediaDirs
+	if opts.Media != "" {
+		mediaDirs = append([]string{opts.Media}, mediaDirs...)
+	}
+	shortcodes, err := g.shortcodeNames()
+	if err != nil {
+		return err
+	}
+
+	// Import oldest first so the earliest post keeps a shared slug
+	sort.SliceStable(source.Posts, func(i, j int) bool { return source.Posts[i].Created.Before(source.Posts[j].Created) })
+
+	var imported, existing []*importedPost
+	drafts, images := 0, 0
+	for _, post := range source.Posts {
+		if strings.TrimSpace(post.Title) == "" {
+			source.Skipped = append(source.Skipped, fmt.Sprintf("%s: no title", post.Source))
+			continue
+		}
+		slug := g.slugify(post.Slug)
+		if slug == "" {
+			slug = g.slugify(post.Title)
+		}
+		if slug == "" {
+			source.Skipped = append(source.Skipped, fmt.Sprintf("%s: title %q has no characters usable in a filename", post.Source, post.Title))
+			continue
+		}
+		post.Slug = slug
+
+		postPath := filepath.Join(g.rootDir, "posts", slug+".md")
+		if _, err := os.Stat(postPath); err == nil && !opts.Force {
+			existing = append(existing, post)
+			continue
+		}
+
+		copied, err := g.importImages(post, mediaDirs)
+		if err != nil {
+			return err
+		}
+		images += copied
+
+		reported := make(map[string]bool)
+		for _, match := range shortcodeNamePattern.FindAllStringSubmatch(post.Body, -1) {
+			switch {
+			case reported[match[0]]:
+			case match[1] == "%":
+				post.Warnings = append(post.Warnings, fmt.Sprintf("shortcode %q uses {{%% %%}}, which isn't supported, change it to {{< >}}", match[2]))
+			case !shortcodes[match[2]]:
+				post.Warnings = append(post.Warnings, fmt.Sprintf("unknown shortcode %q left in the body", match[2]))
+			}
+			reported[match[0]] = true
+		}
+
+		if err := g.writeImportedPost(postPath, post, opts.Section); err != nil {
+			return err
+		}
+		imported = append(imported, post)
+		if post.Draft {
+			drafts++
+		}
+	}
+
+	report := filepath.Join(g.rootDir, importReportFile)
+	file, err := os.Create(report)
+	if err != nil {
+		return fmt.Errorf("failed to write import report: %w", err)
+	}
+	defer file.Close()
+	writeImportReport(file, opts.File, from, imported, existing, source.Skipped)
+
+	warnings := 0
+	for _, post := range imported {
+		warnings += len(post.Warnings)
+	}
+	fmt.Printf("Imported %d posts from %s (%d drafts, %d images copied): %d already existed, %d skipped, %d warnings\n",
+		len(imported), filepath.Base(opts.File), drafts, images, len(existing), len(source.Skipped), warnings)
+	fmt.Printf("Wrote %s\n", importReportFile)
+	return nil
+}
+
+// shortcodeNames lists the shortcodes in templates/shortcodes.
+func (g *Generator) shortcodeNames() (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(g.rootDir, "templates", "shortcodes", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, file := range files {
+		names[strings.TrimSuffix(filepath.Base(file), ".html")] = true
+	}
+	return names, nil
+}
+
+func (g *Generator) writeImportedPost(postPath string, post *importedPost, section string) error {
+	if section == "" {
+		section = "Notes"
+	}
+	created := post.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	updated := post.Updated
+	if updated.Before(created) {
+		updated = created
+	}
+
+	var content strings.Builder
+	content.WriteString("---\n")
+	fmt.Fprintf(&content, "title: %s\n", quoteYAML(post.Title))
+	fmt.Fprintf(&content, "description: %s\n", quoteYAML(post.Description))
+	fmt.Fprintf(&content, "section: %s\n", quoteYAML(section))
+	fmt.Fprintf(&content, "tags: %s\n", quoteYAML(strings.Join(post.Tags, ", ")))
+	fmt.Fprintf(&content, "created: %s\n", quoteYAML(g.formatDate(created)))
+	fmt.Fprintf(&content, "updated: %s\n", quoteYAML(g.formatDate(updated)))
+	content.WriteString("type: \"note\"\n")
+	if post.Cover != "" {
+		fmt.Fprintf(&content, "cover: %s\n", quoteYAML(post.Cover))
+	}
+	if post.Draft {
+		content.WriteString("published: \"false\"\n")
+	}
+	content.WriteString("---\n\n")
+	content.WriteString(strings.TrimSpace(post.Body))
+	content.WriteString("\n")
+
+	if err := os.MkdirAll(filepath.Dir(postPath), 0755); err != nil {
+		return fmt.Errorf("failed to create posts directory: %w", err)
+	}
+	if err := os.WriteFile(postPath, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", postPath, err)
+	}
+	return nil
+}
+
+// importImages copies the images a post uses into images/<slug>/ and points
+// the body and cover at the copies. Images that can't be found are left
+// pointing at their old URL and reported.
+func (g *Generator) importImages(post *importedPost, mediaDirs []string) (int, error) {
+	copied := make(map[string]string)
+	var copyErr error
+
+	rewrite := func(src string) string {
+		if copyErr != nil {
+			return src
+		}
+		if local, ok := copied[src]; ok {
+			return local
+		}
+		file := findImportImage(src, post.Dir, mediaDirs)
+		if file == "" {
+			if !strings.HasPrefix(src, "data:") {
+				post.Warnings = append(post.Warnings, fmt.Sprintf("image not found: %s", src))
+			}
+			copied[src] = src
+			return src
+		}
+
+		local, err := g.copyImportImage(file, post.Slug)
+		if err != nil {
+			copyErr = err
+			return src
+		}
+		copied[src] = local
+		return local
+	}
+
+	post.Body = markdownImagePattern.ReplaceAllStringFunc(post.Body, func(match string) string {
+		parts := markdownImagePattern.FindStringSubmatch(match)
+		src := strings.Trim(parts[2], "<>")
+		return parts[1] + rewrite(src)
+	})
+	post.Body = srcAttrPattern.ReplaceAllStringFunc(post.Body, func(match string) string {
+		parts := srcAttrPattern.FindStringSubmatch(match)
+		quote := parts[2][:1]
+		return parts[1] + quote + rewrite(parts[2][1:len(parts[2])-1]) + quote
+	})
+	if post.Cover != "" {
+		post.Cover = strings.TrimPrefix(rewrite(post.Cover), "/")
+	}
+
+	if copyErr != nil {
+		return 0, copyErr
+	}
+	count := 0
+	for src, local := range copied {
+		if src != local {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// findImportImage looks for an image from an old site on disk. Relative
+// paths are tried against the post's directory, and absolute paths and URLs
+// against each media directory with less and less of their path, so
+// /wp-content/uploads/2019/05/a.jpg is found in a copy of uploads/.
+func findImportImage(src, postDir string, mediaDirs []string) string {
+	parsed, err := url.Parse(src)
+	if err != nil || parsed.Path == "" || strings.HasPrefix(src, "data:") {
+		return ""
+	}
+	imagePath, err := url.PathUnescape(parsed.Path)
+	if err != nil {
+		imagePath = parsed.Path
+	}
+
+	exists := func(file string) bool {
+		info, err := os.Stat(file)
+		return err == nil && !info.IsDir()
+	}
+
+	if parsed.Scheme == "" && parsed.Host == "" && !strings.HasPrefix(imagePath, "/") {
+		if postDir != "" && exists(filepath.Join(postDir, filepath.FromSlash(imagePath))) {
+			return filepath.Join(postDir, filepath.FromSlash(imagePath))
+		}
+	}
+
+	parts := strings.Split(strings.Trim(path.Clean("/"+imagePath), "/"), "/")
+	for i := range parts {
+		suffix := filepath.Join(parts[i:]...)
+		for _, dir := range mediaDirs {
+			if file := filepath.Join(dir, suffix); exists(file) {
+				return file
+			}
+		}
+	}
+	return ""
+}
+
+// copyImportImage copies an image into images/<slug>/ and returns its URL,
+// adding a number to the name if a different file already has it.
+func (g *Generator) copyImportImage(file, slug string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image %s: %w", file, err)
+	}
+	dir := filepath.Join(g.rootDir, "images", slug)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	ext := filepath.Ext(file)
+	base := strings.TrimSuffix(filepath.Base(file), ext)
+	name := base + ext
+	for n := 2; ; n++ {
+		existing, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if string(existing) == string(content) {
+			return "/images/" + slug + "/" + name, nil
+		}
+		name = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+		return "", fmt.Errorf("failed to copy image %s: %w", file, err)
+	}
+	return "/images/" + slug + "/" + name, nil
+}
+
+func writeImportReport(w io.Writer, file, from string, imported, existing []*importedPost, skipped []string) {
+	fmt.Fprintf(w, "# Import report\n\n")
+	fmt.Fprintf(w, "Imported %d posts from `%s` (%s) on %s.\n", len(imported), filepath.Base(file), from, time.Now().Format("January 2, 2006"))
+
+	if len(imported) > 0 {
+		fmt.Fprintf(w, "\n## Imported\n\n")
+		for _, post := range imported {
+			draft := ""
+			if post.Draft {
+				draft = " (draft)"
+			}
+			fmt.Fprintf(w, "- `posts/%s.md`%s from %s\n", post.Slug, draft, post.Source)
+			for _, warning := range post.Warnings {
+				fmt.Fprintf(w, "  - %s\n", warning)
+			}
+		}
+	}
+
+	if len(existing) > 0 {
+		fmt.Fprintf(w, "\n## Already imported\n\nThese posts already exist and were left alone (use `-force` to replace them):\n\n")
+		for _, post := range existing {
+			fmt.Fprintf(w, "- `posts/%s.md` from %s\n", post.Slug, post.Source)
+		}
+	}
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "\n## Skipped\n\n")
+		for _, reason := range skipped {
+			fmt.Fprintf(w, "- %s\n", reason)
+		}
+	}
+}
+
+// parseImportDate reads the date formats export tools write.
+func parseImportDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	layouts := []string{
+		time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04", "2006-01-02", time.RFC1123Z, time.RFC1123, "January 2, 2006",
+	}
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,207 @@
+package site
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ghostImporter reads a Ghost JSON export (Settings → Labs → Export).
+type ghostImporter struct{}
+
+type ghostExport struct {
+	DB []struct {
+		Data ghostData `json:"data"`
+	} `json:"db"`
+
+	// Exports from before Ghost 1.0 may not be wrapped in db
+	Data *ghostData `json:"data"`
+}
+
+type ghostData struct {
+	Posts     []ghostPost `json:"posts"`
+	Tags      []ghostTag  `json:"tags"`
+	PostsTags []struct {
+		PostID    ghostID `json:"post_id"`
+		TagID     ghostID `json:"tag_id"`
+		SortOrder int     `json:"sort_order"`
+	} `json:"posts_tags"`
+}
+
+type ghostPost struct {
+	ID              ghostID     `json:"id"`
+	Title           string      `json:"title"`
+	Slug            string      `json:"slug"`
+	HTML            string      `json:"html"`
+	Markdown        string      `json:"markdown"`
+	Mobiledoc       string      `json:"mobiledoc"`
+	FeatureImage    string      `json:"feature_image"`
+	Status          string      `json:"status"`
+	Type            string      `json:"type"`
+	Page            interface{} `json:"page"`
+	CustomExcerpt   string      `json:"custom_excerpt"`
+	MetaDescription string      `json:"meta_description"`
+	PublishedAt     ghostTime   `json:"published_at"`
+	CreatedAt       ghostTime   `json:"created_at"`
+	UpdatedAt       ghostTime   `json:"updated_at"`
+}
+
+type ghostTag struct {
+	ID         ghostID `json:"id"`
+	Name       string  `json:"name"`
+	Visibility string  `json:"visibility"`
+}
+
+// ghostID is an ObjectID string in current exports and a number in old ones.
+type ghostID string
+
+func (id *ghostID) UnmarshalJSON(data []byte) error {
+	*id = ghostID(strings.Trim(string(data), `"`))
+	return nil
+}
+
+// ghostTime is an ISO date in current exports and milliseconds in old ones.
+type ghostTime struct{ time.Time }
+
+func (t *ghostTime) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t.Time = time.UnixMilli(ms).UTC()
+		return nil
+	}
+	if date, ok := parseImportDate(value); ok {
+		t.Time = date
+	}
+	return nil
+}
+
+// ghostURLPlaceholder stands for the site's URL in exported content.
+const ghostURLPlaceholder = "__GHOST_URL__"
+
+func (ghostImporter) detect(path string) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		return false
+	}
+	content, err := os.ReadFile(path)
+	return err == nil && bytes.Contains(content, []byte(`"posts"`))
+}
+
+func (ghostImporter) read(path string) (*importSource, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var export ghostExport
+	if err := json.Unmarshal(content, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	data := export.Data
+	if len(export.DB) > 0 {
+		data = &export.DB[0].Data
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%s has no posts, is it a Ghost export?", filepath.Base(path))
+	}
+
+	// Internal tags start with # and only drive themes
+	tagNames := make(map[ghostID]string)
+	for _, tag := range data.Tags {
+		if tag.Visibility != "internal" && !strings.HasPrefix(tag.Name, "#") {
+			tagNames[tag.ID] = tag.Name
+		}
+	}
+	sort.SliceStable(data.PostsTags, func(i, j int) bool { return data.PostsTags[i].SortOrder < data.PostsTags[j].SortOrder })
+	postTags := make(map[ghostID][]string)
+	for _, link := range data.PostsTags {
+		if name := tagNames[link.TagID]; name != "" {
+			postTags[link.PostID] = appendTag(postTags[link.PostID], name)
+		}
+	}
+
+	source := &importSource{MediaDirs: []string{filepath.Dir(path)}}
+	for _, p := range data.Posts {
+		if p.Type == "page" || p.Page == true || p.Page == 1.0 {
+			source.Skipped = append(source.Skipped, fmt.Sprintf("/%s/: %q is a page", p.Slug, p.Title))
+			continue
+		}
+
+		post := &importedPost{
+			Title:       strings.TrimSpace(p.Title),
+			Slug:        p.Slug,
+			Description: strings.TrimSpace(p.CustomExcerpt),
+			Tags:        postTags[p.ID],
+			Created:     p.PublishedAt.Time,
+			Updated:     p.UpdatedAt.Time,
+			Draft:       p.Status != "published",
+			Cover:       strings.ReplaceAll(p.FeatureImage, ghostURLPlaceholder, ""),
+			Source:      "/" + p.Slug + "/",
+		}
+		if post.Description == "" {
+			post.Description = strings.TrimSpace(p.MetaDescription)
+		}
+		if post.Created.IsZero() {
+			post.Created = p.CreatedAt.Time
+		}
+
+		// Prefer the markdown posts were written in, if the export has it
+		markdown := p.Markdown
+		if markdown == "" {
+			markdown = ghostMobiledocMarkdown(p.Mobiledoc)
+		}
+		if markdown != "" {
+			post.Body = strings.ReplaceAll(markdown, ghostURLPlaceholder, "")
+		} else if p.HTML != "" {
+			converter := &htmlConverter{}
+			post.Body = converter.convert(strings.ReplaceAll(p.HTML, ghostURLPlaceholder, ""))
+			post.Warnings = append(post.Warnings, converter.warnings...)
+		} else {
+			post.Warnings = append(post.Warnings, "no HTML or markdown body in the export")
+		}
+		source.Posts = append(source.Posts, post)
+	}
+	return source, nil
+}
+
+// ghostMobiledocMarkdown reads a mobiledoc made only of markdown cards, as
+// posts written in Ghost's markdown editor are. Anything else is left to the
+// HTML.
+func ghostMobiledocMarkdown(mobiledoc string) string {
+	if mobiledoc == "" {
+		return ""
+	}
+	var doc struct {
+		Cards    [][]json.RawMessage `json:"cards"`
+		Sections [][]json.RawMessage `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(mobiledoc), &doc); err != nil {
+		return ""
+	}
+	// Section type 10 places a card; others hold rich text
+	for _, section := range doc.Sections {
+		if len(section) == 0 || string(section[0]) != "10" {
+			return ""
+		}
+	}
+	var parts []string
+	for _, card := range doc.Cards {
+		if len(card) < 2 {
+			continue
+		}
+		var name string
+		var payload struct {
+			Markdown string `json:"markdown"`
+		}
+		if json.Unmarshal(card[0], &name) != nil || (name != "markdown" && name != "card-markdown") || json.Unmarshal(card[1], &payload) != nil {
+			return ""
+		}
+		parts = append(parts, strings.TrimSpace(payload.Markdown))
+	}
+	return strings.Join(parts, "\n\n")
+}
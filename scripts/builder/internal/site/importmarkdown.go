@@ -0,0 +1,409 @@
+package site
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// markdownImporter reads the content directory of a Hugo site or the
+// _posts and _drafts of a Jekyll site.
+type markdownImporter struct {
+	jekyll bool
+}
+
+var (
+	jekyllFilenamePattern  = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+	jekyllHighlightPattern = regexp.MustCompile(`\{%-?\s*highlight\s+([\w+-]+)[^%]*-?%\}\n?`)
+	jekyllEndHighlight     = regexp.MustCompile(`\n?\{%-?\s*endhighlight\s*-?%\}`)
+	jekyllRawPattern       = regexp.MustCompile(`\{%-?\s*(?:end)?raw\s*-?%\}`)
+	jekyllSiteURLPattern   = regexp.MustCompile(`\{\{\s*site\.(?:baseurl|url)\s*\}\}`)
+	jekyllURLFilterPattern = regexp.MustCompile(`\{\{\s*["']([^"']+)["']\s*\|\s*(?:relative_url|absolute_url)\s*\}\}`)
+	jekyllPostLinkPattern  = regexp.MustCompile(`\[([^\]]+)\]\(\{%\s*post_url\s+([^\s%]+)\s*%\}\)`)
+	hugoRefLinkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(\{\{[<%]\s*(?:rel)?ref\s+"([^"]+)"\s*[>%]\}\}\)`)
+	hugoRefAnchorPattern   = regexp.MustCompile(`<a\s+href="\{\{[<%]\s*(?:rel)?ref\s+"([^"]+)"\s*[>%]\}\}"\s*>([^<]+)</a>`)
+	hugoRefPattern         = regexp.MustCompile(`\{\{[<%]\s*(?:rel)?ref\s+"([^"]+)"\s*[>%]\}\}`)
+	liquidTagPattern       = regexp.MustCompile(`\{%-?\s*(\w+)`)
+)
+
+// markdownExtensions are the files read as posts.
+var markdownExtensions = map[string]bool{".md": true, ".markdown": true, ".mdown": true}
+
+func (m markdownImporter) detect(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	if !m.jekyll {
+		return true
+	}
+	if filepath.Base(path) == "_posts" {
+		return true
+	}
+	_, err = os.Stat(filepath.Join(path, "_posts"))
+	return err == nil
+}
+
+func (m markdownImporter) read(root string) (*importSource, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s isn't a directory", root)
+	}
+
+	source := &importSource{MediaDirs: m.mediaDirs(root)}
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !markdownExtensions[strings.ToLower(filepath.Ext(name))] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// Hugo section pages list posts rather than being one
+		if strings.HasPrefix(name, "_index.") {
+			source.Skipped = append(source.Skipped, fmt.Sprintf("%s: a section list page", rel))
+			return nil
+		}
+		// A Jekyll site root also has pages and includes outside _posts
+		inDrafts := strings.Contains("/"+rel, "/_drafts/")
+		if m.jekyll && !inDrafts && !strings.Contains("/"+rel, "/_posts/") && filepath.Base(root) != "_posts" {
+			return nil
+		}
+
+		post, err := m.readPost(path, rel)
+		if err != nil {
+			return err
+		}
+		if inDrafts {
+			post.Draft = true
+		}
+		source.Posts = append(source.Posts, post)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	return source, nil
+}
+
+// mediaDirs finds where a site keeps images: Hugo's static/ and assets/ and
+// the root of a Jekyll site, whose images are usually under assets/.
+func (m markdownImporter) mediaDirs(root string) []string {
+	dirs := []string{root}
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return dirs
+	}
+	for {
+		for _, marker := range []string{"_config.yml", "_config.toml", "hugo.toml", "hugo.yaml", "config.toml", "config.yaml", "static"} {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return append(dirs, dir, filepath.Join(dir, "static"), filepath.Join(dir, "assets"))
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dirs
+		}
+		dir = parent
+	}
+}
+
+func (m markdownImporter) readPost(path, rel string) (*importedPost, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields, body := parseForeignFrontmatter(strings.ReplaceAll(string(content), "\r\n", "\n"))
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if values := fields[key]; len(values) > 0 && values[0] != "" {
+				return values[0]
+			}
+		}
+		return ""
+	}
+
+	// Page bundles are named after their directory
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if name == "index" {
+		name = filepath.Base(filepath.Dir(path))
+	}
+	var fileDate time.Time
+	if match := jekyllFilenamePattern.FindStringSubmatch(name); match != nil {
+		fileDate, _ = time.Parse("2006-01-02", match[1])
+		name = match[2]
+	}
+
+	post := &importedPost{
+		Title:       first("title"),
+		Slug:        first("slug"),
+		Description: first("description", "summary", "excerpt", "subtitle"),
+		Cover:       first("cover", "cover.image", "image", "featured_image", "feature_image", "featureImage", "images", "header.image", "thumbnail"),
+		Draft:       first("draft") == "true" || first("published") == "false",
+		Source:      rel,
+		Dir:         filepath.Dir(path),
+	}
+	if post.Slug == "" {
+		post.Slug = name
+	}
+	for _, key := range []string{"tags", "categories", "category"} {
+		for _, tag := range fields[key] {
+			post.Tags = appendTag(post.Tags, tag)
+		}
+	}
+
+	if date, ok := parseImportDate(first("date", "publishDate")); ok {
+		post.Created = date
+	} else {
+		post.Created = fileDate
+	}
+	if date, ok := parseImportDate(first("lastmod", "last_modified_at", "updated", "modified")); ok {
+		post.Updated = date
+	}
+	if post.Created.IsZero() {
+		post.Warnings = append(post.Warnings, "no date, imported with today's")
+	}
+
+	post.Body = m.convertBody(body, post)
+	return post, nil
+}
+
+// convertBody rewrites the Liquid tags and ref shortcodes that have an
+// equivalent here. Links to other posts become [[slug]] links, so the posts
+// they point at need importing too.
+func (m markdownImporter) convertBody(body string, post *importedPost) string {
+	postLink := func(pattern *regexp.Regexp, slugOf func(string) string) {
+		body = pattern.ReplaceAllStringFunc(body, func(match string) string {
+			parts := pattern.FindStringSubmatch(match)
+			return "[[" + slugOf(parts[2]) + "|" + parts[1] + "]]"
+		})
+	}
+
+	if !m.jekyll {
+		postLink(hugoRefLinkPattern, hugoRefSlug)
+		body = hugoRefAnchorPattern.ReplaceAllStringFunc(body, func(match string) string {
+			parts := hugoRefAnchorPattern.FindStringSubmatch(match)
+			return "[[" + hugoRefSlug(parts[1]) + "|" + parts[2] + "]]"
+		})
+		// A ref on its own becomes a link titled after the post
+		body = hugoRefPattern.ReplaceAllStringFunc(body, func(match string) string {
+			return "[[" + hugoRefSlug(hugoRefPattern.FindStringSubmatch(match)[1]) + "]]"
+		})
+		return strings.TrimSpace(body)
+	}
+
+	body = jekyllHighlightPattern.ReplaceAllString(body, "```$1\n")
+	body = jekyllEndHighlight.ReplaceAllString(body, "\n```")
+	body = jekyllRawPattern.ReplaceAllString(body, "")
+	body = jekyllSiteURLPattern.ReplaceAllString(body, "")
+	body = jekyllURLFilterPattern.ReplaceAllString(body, "$1")
+	postLink(jekyllPostLinkPattern, func(target string) string {
+		name := filepath.Base(target)
+		if match := jekyllFilenamePattern.FindStringSubmatch(name); match != nil {
+			name = match[2]
+		}
+		return name
+	})
+
+	reported := make(map[string]bool)
+	for _, match := range liquidTagPattern.FindAllStringSubmatch(body, -1) {
+		if !reported[match[1]] {
+			reported[match[1]] = true
+			post.Warnings = append(post.Warnings, fmt.Sprintf("Liquid tag {%% %s %%} left in the body", match[1]))
+		}
+	}
+	return strings.TrimSpace(body)
+}
+
+// hugoRefSlug is the slug of the post a Hugo ref points at: its filename,
+// or its directory for a page bundle, without any #anchor.
+func hugoRefSlug(target string) string {
+	target, _, _ = strings.Cut(target, "#")
+	name := strings.TrimSuffix(filepath.Base(strings.TrimSuffix(target, "/")), filepath.Ext(target))
+	if name == "index" || name == "_index" {
+		name = filepath.Base(filepath.Dir(target))
+	}
+	return name
+}
+
+// parseForeignFrontmatter reads the YAML (---) or TOML (+++) frontmatter of
+// another generator's content. Every value is a list so tags read the same
+// whether written inline or as a block; nested keys are joined with dots.
+func parseForeignFrontmatter(content string) (map[string][]string, string) {
+	fields := make(map[string][]string)
+	delimiter := ""
+	switch {
+	case strings.HasPrefix(content, "---\n"):
+		delimiter = "---"
+	case strings.HasPrefix(content, "+++\n"):
+		delimiter = "+++"
+	default:
+		return fields, content
+	}
+
+	lines := strings.Split(content, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \t") == delimiter {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return fields, content
+	}
+	body := strings.Join(lines[end+1:], "\n")
+	if delimiter == "+++" {
+		parseTOMLFields(lines[1:end], fields)
+	} else {
+		parseYAMLFields(lines[1:end], fields)
+	}
+	return fields, body
+}
+
+func parseYAMLFields(lines []string, fields map[string][]string) {
+	parent := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if parent != "" {
+				fields[parent] = append(fields[parent], foreignScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		value = strings.TrimSpace(value)
+		if indented && parent != "" {
+			key = parent + "." + key
+		} else if !indented {
+			parent = key
+		}
+
+		switch {
+		case value == "":
+		case value == "|" || value == ">" || value == "|-" || value == ">-":
+			// Block scalars run until the indentation ends
+			var block []string
+			for i+1 < len(lines) && (strings.TrimSpace(lines[i+1]) == "" || lines[i+1][0] == ' ' || lines[i+1][0] == '\t') {
+				i++
+				block = append(block, strings.TrimSpace(lines[i]))
+			}
+			separator := " "
+			if value[0] == '|' {
+				separator = "\n"
+			}
+			fields[key] = []string{strings.TrimSpace(strings.Join(block, separator))}
+		default:
+			fields[key] = foreignValues(value)
+		}
+	}
+}
+
+func parseTOMLFields(lines []string, fields map[string][]string) {
+	table := ""
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			table = strings.Trim(trimmed, "[] ") + "."
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		// Arrays may span lines
+		for strings.HasPrefix(value, "[") && !strings.HasSuffix(value, "]") && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(lines[i])
+		}
+		fields[table+strings.Trim(strings.TrimSpace(key), `"'`)] = foreignValues(value)
+	}
+}
+
+// foreignValues reads a scalar or an inline [a, "b"] list.
+func foreignValues(value string) []string {
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return []string{foreignScalar(value)}
+	}
+	var values []string
+	for _, item := range splitInlineList(value[1 : len(value)-1]) {
+		if item = foreignScalar(strings.TrimSpace(item)); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// splitInlineList splits on commas outside quotes.
+func splitInlineList(list string) []string {
+	var items []string
+	var quote rune
+	start := 0
+	for i, r := range list {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, list[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, list[start:])
+}
+
+func foreignScalar(value string) string {
+	if len(value) > 0 && value[0] != '"' && value[0] != '\'' {
+		// Unquoted values can end in a comment
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		return value
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	return unquoteYAML(value)
+}
@@ -0,0 +1,13 @@
+package site
+
+import "testing"
+
+func TestConvertHugoRefs(t *testing.T) {
+	body := `See [the last post]({{< ref "posts/first.md" >}}), <a href="{{< relref "second/index.md" >}}">this one</a> and {{% ref "third.md#notes" %}}.`
+	want := `See [[first|the last post]], [[second|this one]] and [[third]].`
+
+	got := markdownImporter{}.convertBody(body, &importedPost{})
+	if got != want {
+		t.Errorf("convertBody = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,209 @@
+package site
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// wordpressImporter reads a WordPress WXR export (Tools → Export).
+type wordpressImporter struct{}
+
+type wxrFile struct {
+	Channel struct {
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title         string        `xml:"title"`
+	Link          string        `xml:"link"`
+	Encoded       []wxrEncoded  `xml:"encoded"`
+	PostID        string        `xml:"post_id"`
+	PostName      string        `xml:"post_name"`
+	PostDate      string        `xml:"post_date"`
+	PostDateGMT   string        `xml:"post_date_gmt"`
+	Modified      string        `xml:"post_modified"`
+	ModifiedGMT   string        `xml:"post_modified_gmt"`
+	Status        string        `xml:"status"`
+	PostType      string        `xml:"post_type"`
+	AttachmentURL string        `xml:"attachment_url"`
+	Categories    []wxrCategory `xml:"category"`
+	Meta          []wxrMeta     `xml:"postmeta"`
+}
+
+// wxrEncoded is content:encoded or excerpt:encoded, told apart by namespace.
+type wxrEncoded struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type wxrCategory struct {
+	Domain   string `xml:"domain,attr"`
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",chardata"`
+}
+
+type wxrMeta struct {
+	Key   string `xml:"meta_key"`
+	Value string `xml:"meta_value"`
+}
+
+var (
+	wpCaptionPattern   = regexp.MustCompile(`(?s)\[caption[^\]]*\](.*?)\[/caption\]`)
+	wpCaptionImage     = regexp.MustCompile(`(?s)^\s*((?:<a[^>]*>)?\s*<img[^>]*>\s*(?:</a>)?)(.*)$`)
+	wpEmbedPattern     = regexp.MustCompile(`\[embed\]([^\[]*)\[/embed\]`)
+	wpShortcodePattern = regexp.MustCompile(`\[([a-z_][\w-]*)(\s[^\]]*)?\]`)
+)
+
+func (wordpressImporter) detect(path string) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".xml") {
+		return false
+	}
+	content, err := os.ReadFile(path)
+	return err == nil && bytes.Contains(content, []byte("wordpress.org/export/"))
+}
+
+func (wordpressImporter) read(path string) (*importSource, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var export wxrFile
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	if err := decoder.Decode(&export); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	// Featured images point at attachments by ID
+	attachments := make(map[string]string)
+	for _, item := range export.Channel.Items {
+		if item.PostType == "attachment" {
+			attachments[item.PostID] = item.AttachmentURL
+		}
+	}
+
+	source := &importSource{MediaDirs: []string{filepath.Dir(path)}}
+	for _, item := range export.Channel.Items {
+		switch {
+		case item.PostType == "page":
+			source.Skipped = append(source.Skipped, fmt.Sprintf("%s: %q is a page", item.Link, item.Title))
+			continue
+		case item.PostType != "post":
+			continue
+		case item.Status == "trash" || item.Status == "auto-draft" || item.Status == "inherit":
+			continue
+		}
+
+		var body, excerpt string
+		for _, encoded := range item.Encoded {
+			switch {
+			case strings.Contains(encoded.XMLName.Space, "excerpt"):
+				excerpt = encoded.Value
+			case strings.Contains(encoded.XMLName.Space, "content"):
+				body = encoded.Value
+			}
+		}
+
+		slug, err := url.PathUnescape(item.PostName)
+		if err != nil {
+			slug = item.PostName
+		}
+		post := &importedPost{
+			Title:       html.UnescapeString(strings.TrimSpace(item.Title)),
+			Slug:        slug,
+			Description: strings.TrimSpace(collapseWhitespace(html.UnescapeString(htmlTagPattern.ReplaceAllString(excerpt, "")))),
+			Draft:       item.Status != "publish",
+			Source:      item.Link,
+		}
+		if post.Source == "" {
+			post.Source = fmt.Sprintf("post %s", item.PostID)
+		}
+
+		// Drafts have a zero GMT date
+		for _, date := range []string{item.PostDateGMT, item.PostDate} {
+			if created, ok := parseImportDate(date); ok && created.Year() > 1 {
+				post.Created = created
+				break
+			}
+		}
+		for _, date := range []string{item.ModifiedGMT, item.Modified} {
+			if updated, ok := parseImportDate(date); ok && updated.Year() > 1 {
+				post.Updated = updated
+				break
+			}
+		}
+
+		for _, category := range item.Categories {
+			if (category.Domain == "category" && category.Nicename != "uncategorized") || category.Domain == "post_tag" {
+				post.Tags = appendTag(post.Tags, html.UnescapeString(strings.TrimSpace(category.Name)))
+			}
+		}
+		for _, meta := range item.Meta {
+			if meta.Key == "_thumbnail_id" {
+				post.Cover = attachments[meta.Value]
+			}
+		}
+
+		post.Body = convertWordPressContent(body, post)
+		source.Posts = append(source.Posts, post)
+	}
+	return source, nil
+}
+
+// convertWordPressContent turns post content into markdown. Captions and
+// embeds become HTML the converter understands first; other shortcodes are
+// left in place and reported.
+func convertWordPressContent(content string, post *importedPost) string {
+	content = wpCaptionPattern.ReplaceAllStringFunc(content, func(match string) string {
+		inner := wpCaptionPattern.FindStringSubmatch(match)[1]
+		parts := wpCaptionImage.FindStringSubmatch(inner)
+		if parts == nil {
+			return inner
+		}
+		return "<figure>" + parts[1] + "<figcaption>" + strings.TrimSpace(parts[2]) + "</figcaption></figure>"
+	})
+	content = wpEmbedPattern.ReplaceAllStringFunc(content, func(match string) string {
+		link := html.EscapeString(strings.TrimSpace(wpEmbedPattern.FindStringSubmatch(match)[1]))
+		return fmt.Sprintf(`<p><a href="%s">%s</a></p>`, link, link)
+	})
+
+	converter := &htmlConverter{paragraphs: true}
+	markdown := converter.convert(content)
+	post.Warnings = append(post.Warnings, converter.warnings...)
+
+	// Shortcodes are found in the HTML, since brackets are escaped in markdown
+	reported := make(map[string]bool)
+	for _, match := range wpShortcodePattern.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		// Plain [text] is common, so only flag what looks like a shortcode
+		if reported[name] || (!strings.Contains(match[2], "=") && !strings.Contains(content, "[/"+name+"]")) {
+			continue
+		}
+		reported[name] = true
+		post.Warnings = append(post.Warnings, fmt.Sprintf("WordPress shortcode [%s] left in the body", name))
+	}
+	return markdown
+}
+
+// appendTag adds a tag unless it's empty or already there.
+func appendTag(tags []string, tag string) []string {
+	if tag == "" {
+		return tags
+	}
+	for _, existing := range tags {
+		if strings.EqualFold(existing, tag) {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
@@ -0,0 +1,384 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadLibraryItems reads library/*.md, falling back to the metadata comments of
+// legacy library/*.html pages that have not been migrated to markdown yet.
+func (g *Generator) loadLibraryItems() ([]*LibraryItem, error) {
+	libraryDir := filepath.Join(g.rootDir, "library")
+	items := []*LibraryItem{}
+	seen := make(map[string]bool)
+
+	markdownFiles, err := filepath.Glob(filepath.Join(libraryDir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range markdownFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata, body, err := g.parseMarkdownFrontmatter(string(content))
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip unpublished items
+		if metadata["published"] == "false" {
+			continue
+		}
+
+		item := &LibraryItem{
+			Title:       metadata["title"],
+			Description: metadata["description"],
+			Author:      metadata["author"],
+			Year:        metadata["year"],
+			Tags:        metadata["tags"],
+			Created:     metadata["created"],
+			Updated:     metadata["updated"],
+			Type:        metadata["type"],
+			Cover:       metadata["cover"],
+			Status:      strings.ToLower(metadata["status"]),
+			Rating:      metadata["rating"],
+			Started:     metadata["started"],
+			Finished:    metadata["finished"],
+			ISBN:        metadata["isbn"],
+			Quotes:      frontmatterList(metadata["quotes"]),
+			Content:     body,
+			ID:          strings.TrimSuffix(filepath.Base(path), ".md"),
+			Filename:    filepath.Base(path),
+			BodyLine:    bodyLine(string(content), body),
+		}
+		items = append(items, item)
+		seen[item.ID] = true
+	}
+
+	htmlFiles, err := filepath.Glob(filepath.Join(libraryDir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range htmlFiles {
+		id := strings.TrimSuffix(filepath.Base(path), ".html")
+		// The generated library index isn't an item
+		if seen[id] || path == g.outputPath(libraryIndexURL) {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata := g.extractHTMLMetadata(string(content))
+		items = append(items, &LibraryItem{
+			Title:       metadata["title"],
+			Description: metadata["description"],
+			Author:      metadata["author"],
+			Year:        metadata["year"],
+			Tags:        metadata["tags"],
+			Created:     metadata["created"],
+			Updated:     metadata["updated"],
+			Type:        metadata["type"],
+			ID:          id,
+			Filename:    filepath.Base(path),
+		})
+	}
+
+	return items, nil
+}
+
+// renderLibraryContent renders the body of a markdown library item to HTML.
+func (g *Generator) renderLibraryContent(item *LibraryItem) (string, error) {
+	content, shortcodes, err := g.expandShortcodes(item.Content, "library/"+item.Filename, item.BodyLine)
+	if err != nil {
+		return "", err
+	}
+
+	htmlContent, err := g.markdownToHTML(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert library/%s to HTML: %w", item.Filename, err)
+	}
+	return restoreShortcodes(htmlContent, shortcodes), nil
+}
+
+func (g *Generator) generateLibraryHTML(item *LibraryItem) (string, error) {
+	htmlContent, err := g.renderLibraryContent(item)
+	if err != nil {
+		return "", err
+	}
+
+	var tagsHTML strings.Builder
+	for _, tag := range splitTags(item.Tags) {
+		tagsHTML.WriteString(fmt.Sprintf(`<span class="post-tag">%s</span>`, template.HTMLEscapeString(tag)))
+	}
+
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
+  <meta name="description" content="{{.Description}}">
+  <meta name="keywords" content="{{.Tags}}">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
+  <title>{{.Title}} - Jordan Joe Cooper</title>
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
+</head>
+<body>
+  <nav class="navbar">
+    <div class="container nav-content">
+      <a href="/" class="logo">J</a>
+      <div class="nav-links">
+        <a href="/index.html#notes">Writing</a>
+        <a href="/about.html">About</a>
+      </div>
+    </div>
+  </nav>
+  <div class="container">
+    <header class="post-heading">
+      <h1>{{.Title}}</h1>
+      <p class="post-description">{{.Description}}</p>
+      <time>{{.Created}}</time>
+    </header>
+    <main>
+      <div class="book-cover-container">
+        {{with .Cover}}<img src="{{.Src}}"{{with .Srcset}} srcset="{{.}}" sizes="300px"{{end}} width="{{.Width}}" height="{{.Height}}" alt="Cover of {{$.Title}}" class="book-cover-image">{{end}}
+        <h2 class="book-author">{{.Author}}</h2>
+      </div>
+      {{- with .Reading}}
+      <dl class="reading-log">
+        {{- with .Status}}
+        <dt>Status</dt><dd>{{.}}</dd>
+        {{- end}}
+        {{- with .Stars}}
+        <dt>Rating</dt><dd class="book-rating" title="{{$.Reading.Rating}} out of 5">{{.}}</dd>
+        {{- end}}
+        {{- with .Started}}
+        <dt>Started</dt><dd><time>{{.}}</time></dd>
+        {{- end}}
+        {{- with .Finished}}
+        <dt>Finished</dt><dd><time>{{.}}</time></dd>
+        {{- end}}
+        {{- with .ISBN}}
+        <dt>ISBN</dt><dd>{{.}}</dd>
+        {{- end}}
+      </dl>
+      {{- end}}
+      <div class="book-content">
+        {{.HTMLContent}}
+      </div>
+      {{- if .Quotes}}
+      <section class="book-quotes">
+        <h2>Quotes</h2>
+        {{- range .Quotes}}
+        <blockquote>{{.}}</blockquote>
+        {{- end}}
+      </section>
+      {{- end}}
+      <footer class="post-footer">
+        <div class="post-metadata-footer">
+          <span>Library</span>
+          <span>•</span>
+          <span>Jordan Joe Cooper</span>
+        </div>
+        <div class="post-tags">
+          {{.TagsHTML}}
+        </div>
+        <div class="post-time">
+          Last updated: <time>{{.Updated}}</time>
+        </div>
+      </footer>
+      {{- if .Backlinks}}
+      <section class="backlinks">
+        <h2>Linked from</h2>
+        <ul>
+          {{- range .Backlinks}}
+          <li><a href="{{.URL}}">{{.Title}}</a></li>
+          {{- end}}
+        </ul>
+      </section>
+      {{- end}}
+      <div class="back-button-container">
+        <a href="/" class="back-button">Back to home</a>
+      </div>
+    </main>
+  </div>
+</body>
+</html>`
+
+	t, err := template.New("library").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	meta, err := g.libraryMeta(item)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":        meta,
+		"Title":       item.Title,
+		"Description": item.Description,
+		"Author":      item.Author,
+		"Tags":        item.Tags,
+		"Created":     item.Created,
+		"Updated":     item.Updated,
+		"Cover":       g.covers[item.ID],
+		"Backlinks":   item.Backlinks,
+		"Reading":     g.readingDetailsFor(item),
+		"Quotes":      item.Quotes,
+		"HTMLContent": template.HTML(htmlContent),
+		"TagsHTML":    template.HTML(tagsHTML.String()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func (g *Generator) UpdateLibrary() error {
+	// Every page links fonts.css, so a missing font fails before anything is written
+	if _, err := g.loadAssets(); err != nil {
+		return err
+	}
+
+	items, err := g.loadLibraryItems()
+	if err != nil {
+		return fmt.Errorf("failed to read library: %w", err)
+	}
+
+	// Backlinks need links from posts as well as other library items
+	posts, err := g.loadPosts()
+	if err != nil {
+		return fmt.Errorf("failed to read posts: %w", err)
+	}
+	if err := g.resolveLinks(posts, items); err != nil {
+		return fmt.Errorf("failed to resolve links:\n%w", err)
+	}
+
+	if err := g.prepareCovers(items); err != nil {
+		return err
+	}
+
+	generated := 0
+	for _, item := range items {
+		// Legacy HTML pages are hand-written, so only their generated blocks change
+		if !strings.HasSuffix(item.Filename, ".md") {
+			if err := g.updateLegacyLibraryPage(item); err != nil {
+				return fmt.Errorf("failed to update library item %s: %w", item.ID, err)
+			}
+			continue
+		}
+
+		htmlContent, err := g.generateLibraryHTML(item)
+		if err != nil {
+			return fmt.Errorf("failed to generate HTML for library item %s: %w", item.ID, err)
+		}
+
+		htmlPath := g.outputPath(g.libraryURL(item))
+		if err := g.writePage(htmlPath, htmlContent); err != nil {
+			return fmt.Errorf("failed to write HTML file for library item %s: %w", item.ID, err)
+		}
+		generated++
+	}
+
+	fmt.Printf("Generated HTML files for %d library items\n", generated)
+
+	// The index and stats cover legacy pages too
+	if err := g.generateLibraryIndex(items); err != nil {
+		return err
+	}
+	if err := g.generateReadingStats(items); err != nil {
+		return err
+	}
+	fmt.Println("Generated library index and reading stats")
+
+	return g.removeStaleCovers()
+}
+
+// Structured data and backlinks are generated into legacy library pages
+// between these markers, which are added the first time a page is updated.
+const (
+	legacyJSONLDStart    = "<!-- jsonld:start -->"
+	legacyJSONLDEnd      = "<!-- jsonld:end -->"
+	legacyBacklinksStart = "<!-- backlinks:start -->"
+	legacyBacklinksEnd   = "<!-- backlinks:end -->"
+)
+
+const legacyBacklinksTemplate = `{{if .}}
+      <section class="backlinks">
+        <h2>Linked from</h2>
+        <ul>
+          {{- range .}}
+          <li><a href="{{.URL}}">{{.Title}}</a></li>
+          {{- end}}
+        </ul>
+      </section>
+      {{end}}`
+
+// updateLegacyLibraryPage refreshes the Book/Review structured data and the
+// backlinks of a hand-written library page, leaving the rest of it as it is.
+func (g *Generator) updateLegacyLibraryPage(item *LibraryItem) error {
+	path := filepath.Join(g.rootDir, "library", item.Filename)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	meta, err := g.libraryMeta(item)
+	if err != nil {
+		return err
+	}
+	jsonLD := "\n  <script type=\"application/ld+json\">" + string(meta.JSONLD) + "</script>\n  "
+
+	t, err := template.New("legacy-backlinks").Parse(legacyBacklinksTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	var backlinks strings.Builder
+	if err := t.Execute(&backlinks, item.Backlinks); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	content := replaceMarked(string(original), legacyJSONLDStart, legacyJSONLDEnd, "</head>", "  ", jsonLD)
+	content = replaceMarked(content, legacyBacklinksStart, legacyBacklinksEnd, `<div class="back-button-container">`, "      ", backlinks.String())
+	if content == string(original) {
+		return nil
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// replaceMarked replaces what's between the start and end markers, or adds
+// the markers and content on their own line above the anchor when the page
+// has none yet.
+func replaceMarked(content, start, end, anchor, indent, replacement string) string {
+	i := strings.Index(content, start)
+	j := strings.Index(content, end)
+	if i >= 0 && j > i {
+		return content[:i+len(start)] + replacement + content[j:]
+	}
+
+	k := strings.Index(content, anchor)
+	if k < 0 {
+		return content
+	}
+	line := strings.LastIndex(content[:k], "\n") + 1
+	return content[:line] + indent + start + replacement + end + "\n" + content[line:]
+}
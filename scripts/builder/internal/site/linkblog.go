@@ -0,0 +1,191 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// linkInfo is what a link post's layout shows about the page it links to.
+type linkInfo struct {
+	URL   string
+	Host  string
+	Quote string
+	Via   *viaInfo
+}
+
+// viaInfo credits where a link was found. Via can be a URL or just a name.
+type viaInfo struct {
+	Name string
+	URL  string
+}
+
+func (g *Generator) linkInfo(post *Post) *linkInfo {
+	if post.Type != "link" || post.LinkURL == "" {
+		return nil
+	}
+	info := &linkInfo{
+		URL:   post.LinkURL,
+		Host:  linkHost(post.LinkURL),
+		Quote: post.Quote,
+	}
+	if post.Via != "" {
+		info.Via = &viaInfo{Name: post.Via}
+		if strings.HasPrefix(post.Via, "http://") || strings.HasPrefix(post.Via, "https://") {
+			info.Via = &viaInfo{Name: linkHost(post.Via), URL: post.Via}
+		}
+	}
+	return info
+}
+
+// linkHost returns the host a URL points to without its www. prefix.
+func linkHost(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return link
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
+}
+
+// linkPosts returns the link posts, newest first.
+func linkPosts(posts []*Post) []*Post {
+	var links []*Post
+	for _, post := range posts {
+		if post.Type == "link" {
+			links = append(links, post)
+		}
+	}
+	sortPostsByDate(links)
+	for i, j := 0, len(links)-1; i < j; i, j = i+1, j-1 {
+		links[i], links[j] = links[j], links[i]
+	}
+	return links
+}
+
+func (g *Generator) generateLinksPage(posts []*Post) error {
+	links := linkPosts(posts)
+	if len(links) == 0 {
+		return nil
+	}
+
+	htmlContent, err := g.generateLinksHTML(links)
+	if err != nil {
+		return fmt.Errorf("failed to generate links page: %w", err)
+	}
+	if err := g.writePage(g.outputPath(g.linksURL()), htmlContent); err != nil {
+		return fmt.Errorf("failed to write links page: %w", err)
+	}
+
+	fmt.Printf("Generated links page with %d links\n", len(links))
+	return nil
+}
+
+func (g *Generator) generateLinksHTML(links []*Post) (string, error) {
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
+  <meta name="description" content="{{.Description}}">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
+  <title>{{.Meta.Title}}</title>
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
+</head>
+<body>
+  <nav class="navbar">
+    <div class="container nav-content">
+      <a href="/" class="logo">J</a>
+      <div class="nav-links">
+        <a href="/index.html#notes">Writing</a>
+        <a href="/about.html">About</a>
+      </div>
+    </div>
+  </nav>
+  <div class="container">
+    <header class="post-heading">
+      <h1>Links</h1>
+      <p class="post-description">{{.Description}}</p>
+    </header>
+    <main>
+      <div class="links-stream">
+        {{- range .Links}}
+        <article class="link-entry">
+          <h3><a href="{{.URL}}">{{.Title}}</a> <span class="link-source">{{.Host}}</span></h3>
+          {{- with .Quote}}
+          <blockquote class="link-quote">{{.}}</blockquote>
+          {{- end}}
+          {{- with .Description}}
+          <p>{{.}}</p>
+          {{- end}}
+          <a href="{{.Permalink}}" class="link-permalink"><time>{{.Created}}</time></a>
+        </article>
+        {{- end}}
+      </div>
+      <div class="back-button-container">
+        <a href="/" class="back-button">Back to home</a>
+      </div>
+    </main>
+  </div>
+</body>
+</html>`
+
+	t, err := template.New("links").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	type linkEntry struct {
+		Title       string
+		Description string
+		URL         string
+		Host        string
+		Quote       string
+		Created     string
+		Permalink   string
+	}
+	var entries []linkEntry
+	for _, post := range links {
+		entry := linkEntry{
+			Title:       post.Title,
+			Description: post.Description,
+			URL:         post.LinkURL,
+			Host:        linkHost(post.LinkURL),
+			Quote:       post.Quote,
+			Created:     post.Created,
+			Permalink:   g.postURL(post),
+		}
+		// Links without a URL are still worth listing as posts
+		if entry.URL == "" {
+			entry.URL = entry.Permalink
+			entry.Host = ""
+		}
+		entries = append(entries, entry)
+	}
+
+	description := "Things worth reading from around the web."
+	meta, err := g.listMeta("Links", description, g.linksURL(), g.pageFeeds("", nil))
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":        meta,
+		"Description": description,
+		"Links":       entries,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
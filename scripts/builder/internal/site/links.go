@@ -0,0 +1,162 @@
+package site
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// wikiLinkPattern matches [[slug]] and [[slug|label]].
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\]|]+)(?:\|([^\]]+))?\]\]`)
+
+type linkTarget struct {
+	link      *postLink
+	backlinks *[]*postLink
+}
+
+// linkTargets indexes posts and library items by slug. Targets can also be
+// addressed as posts/<slug> or library/<id> when a bare slug is ambiguous.
+func (g *Generator) linkTargets(posts []*Post, items []*LibraryItem) map[string]linkTarget {
+	targets := make(map[string]linkTarget)
+
+	for _, item := range items {
+		target := linkTarget{
+			link:      &postLink{Title: item.Title, Description: item.Description, URL: g.libraryURL(item)},
+			backlinks: &item.Backlinks,
+		}
+		targets["library/"+item.ID] = target
+		targets[item.ID] = target
+	}
+
+	// Posts win over library items for bare slugs
+	for _, post := range posts {
+		target := linkTarget{link: g.linkTo(post), backlinks: &post.Backlinks}
+		targets["posts/"+post.Slug] = target
+		targets[post.Slug] = target
+	}
+
+	return targets
+}
+
+// resolveWikiLinks rewrites [[slug]] links in markdown into regular links,
+// leaving fenced code blocks and inline code alone. It returns the targets
+// that were linked to and any unknown slugs.
+func resolveWikiLinks(content string, targets map[string]linkTarget) (string, []linkTarget, []string) {
+	var linked []linkTarget
+	var unknown []string
+
+	lines := strings.Split(content, "\n")
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.Contains(line, "[[") {
+			continue
+		}
+
+		// Odd segments of a backtick split are inline code
+		segments := strings.Split(line, "`")
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = wikiLinkPattern.ReplaceAllStringFunc(segments[j], func(match string) string {
+				parts := wikiLinkPattern.FindStringSubmatch(match)
+				slug := strings.TrimSpace(parts[1])
+				target, ok := targets[slug]
+				if !ok {
+					unknown = append(unknown, slug)
+					return match
+				}
+
+				label := strings.TrimSpace(parts[2])
+				if label == "" {
+					label = target.link.Title
+				}
+				linked = append(linked, target)
+				return fmt.Sprintf("[%s](%s)", label, target.link.URL)
+			})
+		}
+		lines[i] = strings.Join(segments, "`")
+	}
+
+	return strings.Join(lines, "\n"), linked, unknown
+}
+
+// resolveLinks rewrites wiki links across all content and fills in the
+// backlinks of every linked page.
+func (g *Generator) resolveLinks(posts []*Post, items []*LibraryItem) error {
+	targets := g.linkTargets(posts, items)
+	for _, target := range targets {
+		*target.backlinks = nil
+	}
+
+	type source struct {
+		file    string
+		content *string
+		link    *postLink
+	}
+	var sources []source
+	for _, post := range posts {
+		// Backlinks share the post's target link, so the description
+		// filled in from its excerpt below reaches both
+		sources = append(sources, source{"posts/" + post.Filename, &post.Content, targets["posts/"+post.Slug].link})
+	}
+	for _, item := range items {
+		// Legacy HTML library pages have no markdown to resolve
+		if strings.HasSuffix(item.Filename, ".md") {
+			link := &postLink{Title: item.Title, Description: item.Description, URL: g.libraryURL(item)}
+			sources = append(sources, source{"library/" + item.Filename, &item.Content, link})
+		}
+	}
+
+	var problems []string
+	for _, src := range sources {
+		resolved, linked, unknown := resolveWikiLinks(*src.content, targets)
+		for _, slug := range unknown {
+			problems = append(problems, fmt.Sprintf("%s: unknown link target [[%s]]", src.file, slug))
+		}
+		*src.content = resolved
+
+		seen := make(map[*[]*postLink]bool)
+		for _, target := range linked {
+			if seen[target.backlinks] || target.link.URL == src.link.URL {
+				continue
+			}
+			seen[target.backlinks] = true
+			*target.backlinks = append(*target.backlinks, src.link)
+		}
+	}
+
+	// Stats and excerpts are read from the resolved markdown, so links in
+	// an excerpt show as their text
+	for _, post := range posts {
+		g.computePostStats(post)
+		if post.Description == "" {
+			post.Description = post.Excerpt
+			targets["posts/"+post.Slug].link.Description = post.Description
+		}
+	}
+
+	for _, target := range targets {
+		backlinks := *target.backlinks
+		sort.SliceStable(backlinks, func(i, j int) bool { return backlinks[i].Title < backlinks[j].Title })
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func (g *Generator) lintLinks(posts []*Post) []string {
+	items, err := g.loadLibraryItems()
+	if err != nil {
+		return []string{fmt.Sprintf("failed to read library: %v", err)}
+	}
+	if err := g.resolveLinks(posts, items); err != nil {
+		return strings.Split(err.Error(), "\n")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package site
+
+import "fmt"
+
+// Lint checks the content for problems that would produce a broken site.
+func (g *Generator) Lint() error {
+	posts, err := g.loadPosts()
+	if err != nil {
+		return fmt.Errorf("failed to read posts: %w", err)
+	}
+
+	var problems []string
+	problems = append(problems, g.lintSeries(posts)...)
+	problems = append(problems, g.lintLinks(posts)...)
+	problems = append(problems, g.lintRedirects(posts)...)
+
+	items, err := g.loadLibraryItems()
+	if err != nil {
+		return fmt.Errorf("failed to read library: %w", err)
+	}
+	problems = append(problems, g.lintLibrary(items)...)
+
+	for _, problem := range problems {
+		fmt.Printf("  %s\n", problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problems", len(problems))
+	}
+
+	fmt.Println("No problems found")
+	return nil
+}
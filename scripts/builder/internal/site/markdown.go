@@ -0,0 +1,152 @@
+package site
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+// MarkdownConfig toggles the optional markdown extensions for the site.
+type MarkdownConfig struct {
+	Footnotes       bool `json:"footnotes"`
+	Callouts        bool `json:"callouts"`
+	DefinitionLists bool `json:"definitionLists"`
+	Math            bool `json:"math"`
+	Diagrams        bool `json:"diagrams"`
+}
+
+// calloutPattern matches GitHub-style alert markers such as [!NOTE].
+var calloutPattern = regexp.MustCompile(`^\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\][ \t]*\r?\n?`)
+
+func (g *Generator) parserExtensions() parser.Extensions {
+	extensions := parser.CommonExtensions
+	if g.config.Markdown.Footnotes {
+		extensions |= parser.Footnotes
+	}
+	if !g.config.Markdown.DefinitionLists {
+		extensions &^= parser.DefinitionLists
+	}
+	if !g.config.Markdown.Math {
+		extensions &^= parser.MathJax
+	}
+	return extensions
+}
+
+// newParser returns a markdown parser with the site's extensions.
+func (g *Generator) newParser() *parser.Parser {
+	p := parser.NewWithExtensions(g.parserExtensions())
+	if g.config.Markdown.Math {
+		p.RegisterInline('$', inlineMath)
+	}
+	return p
+}
+
+func (g *Generator) rendererOptions() html.RendererOptions {
+	flags := html.CommonFlags | html.HrefTargetBlank
+	if g.config.Markdown.Footnotes {
+		flags |= html.FootnoteReturnLinks
+	}
+	return html.RendererOptions{
+		Flags:                      flags,
+		FootnoteReturnLinkContents: `<span aria-label="Back to content">↩</span>`,
+	}
+}
+
+// renderHooks prepares the parsed document and returns the hooks that
+// render the site's custom nodes. Hooks can't fail the render directly, so
+// they record errors in errs instead.
+func (g *Generator) renderHooks(doc ast.Node, errs *[]error) []html.RenderNodeFunc {
+	var hooks []html.RenderNodeFunc
+	if g.config.Markdown.Callouts {
+		hooks = append(hooks, calloutHook(findCallouts(doc)))
+	}
+	if g.config.Markdown.Math {
+		hooks = append(hooks, mathHook(errs))
+	}
+	if g.config.Markdown.Diagrams {
+		hooks = append(hooks, g.diagramHook(errs))
+	}
+	return hooks
+}
+
+// chainHooks runs each hook in turn until one handles the node.
+func chainHooks(hooks []html.RenderNodeFunc) html.RenderNodeFunc {
+	if len(hooks) == 0 {
+		return nil
+	}
+	return func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+		for _, hook := range hooks {
+			if status, handled := hook(w, node, entering); handled {
+				return status, true
+			}
+		}
+		return ast.GoToNext, false
+	}
+}
+
+// findCallouts strips the [!TYPE] marker from blockquotes that start with
+// one and returns the callout type of each.
+func findCallouts(doc ast.Node) map[*ast.BlockQuote]string {
+	callouts := make(map[*ast.BlockQuote]string)
+
+	ast.WalkFunc(doc, func(node ast.Node, entering bool) ast.WalkStatus {
+		quote, ok := node.(*ast.BlockQuote)
+		if !ok || !entering {
+			return ast.GoToNext
+		}
+
+		paragraph, ok := ast.GetFirstChild(quote).(*ast.Paragraph)
+		if !ok {
+			return ast.GoToNext
+		}
+		text, ok := ast.GetFirstChild(paragraph).(*ast.Text)
+		if !ok {
+			return ast.GoToNext
+		}
+
+		match := calloutPattern.FindSubmatch(text.Literal)
+		if match == nil {
+			return ast.GoToNext
+		}
+		callouts[quote] = strings.ToLower(string(match[1]))
+		text.Literal = bytes.TrimLeft(text.Literal[len(match[0]):], " \t\r\n")
+
+		// A marker on its own line leaves an empty paragraph behind
+		if len(text.Literal) == 0 && len(paragraph.Children) == 1 {
+			ast.RemoveFromTree(paragraph)
+		}
+		return ast.GoToNext
+	})
+
+	return callouts
+}
+
+// calloutHook renders marked blockquotes as <aside> elements.
+func calloutHook(callouts map[*ast.BlockQuote]string) html.RenderNodeFunc {
+	return func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+		quote, ok := node.(*ast.BlockQuote)
+		if !ok {
+			return ast.GoToNext, false
+		}
+		kind, ok := callouts[quote]
+		if !ok {
+			return ast.GoToNext, false
+		}
+
+		if entering {
+			title := strings.ToUpper(kind[:1]) + kind[1:]
+			fmt.Fprintf(w, "<aside class=\"callout callout-%s\" role=\"note\">\n<p class=\"callout-title\">%s</p>\n",
+				kind, template.HTMLEscapeString(title))
+		} else {
+			io.WriteString(w, "</aside>\n")
+		}
+		return ast.GoToNext, true
+	}
+}
@@ -0,0 +1,64 @@
+package site
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestMarkdownGolden renders each testdata/markdown/<name>.md and compares it
+// with <name>.html. A <name>.json beside the input is used as site.json, so a
+// case can turn extensions off.
+func TestMarkdownGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden inputs in testdata/markdown")
+	}
+
+	for _, input := range inputs {
+		base := strings.TrimSuffix(input, ".md")
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			g := NewGenerator()
+			g.rootDir = t.TempDir()
+			if config, err := os.ReadFile(base + ".json"); err == nil {
+				if err := os.WriteFile(filepath.Join(g.rootDir, "site.json"), config, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := g.LoadConfig(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := g.markdownToHTML(string(source))
+			if err != nil {
+				t.Fatalf("failed to render %s: %v", input, err)
+			}
+
+			golden := base + ".html"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s (run go test -update to create it): %v", golden, err)
+			}
+			if got != string(want) {
+				t.Errorf("%s doesn't match %s\ngot:\n%s\nwant:\n%s", input, golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,514 @@
+package site
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+// mathIdentifiers are commands rendered as <mi>.
+var mathIdentifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "rho": "ρ", "sigma": "σ",
+	"tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "ell": "ℓ", "hbar": "ℏ", "emptyset": "∅",
+}
+
+// mathOperators are commands rendered as <mo>.
+var mathOperators = map[string]string{
+	"cdot": "⋅", "times": "×", "div": "÷", "pm": "±", "mp": "∓", "ast": "∗",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "propto": "∝",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"leftrightarrow": "↔", "iff": "⇔", "implies": "⟹", "mapsto": "↦",
+	"in": "∈", "notin": "∉", "subset": "⊂", "subseteq": "⊆", "supset": "⊃", "supseteq": "⊇",
+	"cup": "∪", "cap": "∩", "setminus": "∖", "forall": "∀", "exists": "∃", "neg": "¬",
+	"land": "∧", "lor": "∨", "wedge": "∧", "vee": "∨", "circ": "∘",
+	"ldots": "…", "cdots": "⋯", "dots": "…", "prime": "′",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"{": "{", "}": "}", "|": "‖",
+}
+
+// mathLargeOperators take limits above and below in display mode.
+var mathLargeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "int": "∫", "iint": "∬", "oint": "∮",
+	"bigcup": "⋃", "bigcap": "⋂", "lim": "lim",
+}
+
+// mathFunctions are upright function names such as \sin.
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true,
+	"log": true, "ln": true, "exp": true, "max": true, "min": true, "sup": true, "inf": true,
+	"det": true, "gcd": true, "deg": true, "dim": true, "arg": true,
+}
+
+var mathAccents = map[string]string{
+	"hat": "^", "bar": "¯", "overline": "¯", "vec": "→", "dot": "˙", "ddot": "¨", "tilde": "~",
+}
+
+var mathSpaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ";": "0.2778em", " ": "0.2778em",
+	"quad": "1em", "qquad": "2em", "!": "-0.1667em",
+}
+
+var mathVariants = map[string]string{
+	"mathbf": "bold", "mathit": "italic", "mathrm": "normal", "mathbb": "double-struck",
+	"mathcal": "script", "mathsf": "sans-serif", "mathtt": "monospace",
+}
+
+// latexToMathML translates a practical subset of LaTeX into presentation MathML.
+func latexToMathML(source string, display bool) (string, error) {
+	p := &mathParser{input: []rune(source), display: display}
+	body, err := p.parseSequence(0)
+	if err != nil {
+		return "", fmt.Errorf("math '%s': %w", strings.TrimSpace(source), err)
+	}
+
+	mode := "inline"
+	if display {
+		mode = "block"
+	}
+	return fmt.Sprintf(`<math xmlns="http://www.w3.org/1998/Math/MathML" display="%s"><semantics><mrow>%s</mrow><annotation encoding="application/x-tex">%s</annotation></semantics></math>`,
+		mode, body, template.HTMLEscapeString(strings.TrimSpace(source))), nil
+}
+
+type mathParser struct {
+	input   []rune
+	pos     int
+	display bool
+}
+
+func (p *mathParser) peek() rune {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *mathParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// parseSequence parses atoms until the closing rune (0 for end of input),
+// consuming the closing rune.
+func (p *mathParser) parseSequence(closing rune) (string, error) {
+	var out strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			if closing != 0 {
+				return "", fmt.Errorf("missing closing %q", closing)
+			}
+			return out.String(), nil
+		}
+		if closing != 0 && p.peek() == closing {
+			p.pos++
+			return out.String(), nil
+		}
+		if closing == 0 && p.peek() == '}' {
+			return "", fmt.Errorf("unexpected }")
+		}
+		if p.peek() == '\\' && p.peekCommand() == "right" {
+			return "", fmt.Errorf("\\right without matching \\left")
+		}
+
+		atom, err := p.parseScripted()
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(atom)
+	}
+}
+
+func (p *mathParser) peekCommand() string {
+	saved := p.pos
+	name := p.readCommand()
+	p.pos = saved
+	return name
+}
+
+// parseScripted parses an atom followed by optional ^ and _ scripts.
+func (p *mathParser) parseScripted() (string, error) {
+	base, large, err := p.parseAtom()
+	if err != nil {
+		return "", err
+	}
+
+	var sub, sup string
+	primes := 0
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '_':
+			if sub != "" {
+				return "", fmt.Errorf("double subscript")
+			}
+			p.pos++
+			if sub, err = p.parseArgument(); err != nil {
+				return "", err
+			}
+			continue
+		case '^':
+			if sup != "" {
+				return "", fmt.Errorf("double superscript")
+			}
+			p.pos++
+			if sup, err = p.parseArgument(); err != nil {
+				return "", err
+			}
+			continue
+		case '\'':
+			p.pos++
+			primes++
+			sup += "<mo>′</mo>"
+			continue
+		}
+		break
+	}
+
+	under, over := "msub", "msup"
+	both := "msubsup"
+	if large && p.display {
+		under, over, both = "munder", "mover", "munderover"
+	}
+
+	// Scripts are a single element unless primes were added alongside them
+	if primes > 1 || primes == 1 && sup != "<mo>′</mo>" {
+		sup = wrapRow(sup)
+	}
+
+	switch {
+	case sub != "" && sup != "":
+		return fmt.Sprintf("<%s>%s%s%s</%s>", both, base, sub, sup, both), nil
+	case sub != "":
+		return fmt.Sprintf("<%s>%s%s</%s>", under, base, sub, under), nil
+	case sup != "":
+		return fmt.Sprintf("<%s>%s%s</%s>", over, base, sup, over), nil
+	}
+	return base, nil
+}
+
+// parseArgument parses a braced group or a single atom.
+func (p *mathParser) parseArgument() (string, error) {
+	p.skipSpace()
+	if p.peek() == '{' {
+		p.pos++
+		group, err := p.parseSequence('}')
+		if err != nil {
+			return "", err
+		}
+		return wrapRow(group), nil
+	}
+	if p.pos >= len(p.input) {
+		return "", fmt.Errorf("missing argument")
+	}
+	atom, _, err := p.parseAtom()
+	return atom, err
+}
+
+// readBraced returns the raw text of a braced argument.
+func (p *mathParser) readBraced() (string, error) {
+	p.skipSpace()
+	if p.peek() != '{' {
+		return "", fmt.Errorf("expected {")
+	}
+	depth := 0
+	start := p.pos + 1
+	for ; p.pos < len(p.input); p.pos++ {
+		switch p.input[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				text := string(p.input[start:p.pos])
+				p.pos++
+				return text, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("missing closing }")
+}
+
+func (p *mathParser) readCommand() string {
+	p.pos++ // backslash
+	start := p.pos
+	for p.pos < len(p.input) && unicode.IsLetter(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start && p.pos < len(p.input) {
+		// Single non-letter command such as \, or \{
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// parseAtom parses a single element and reports whether it is a large
+// operator that takes limits.
+func (p *mathParser) parseAtom() (string, bool, error) {
+	r := p.peek()
+	switch {
+	case r == '{':
+		p.pos++
+		group, err := p.parseSequence('}')
+		return wrapRow(group), false, err
+	case r == '\\':
+		return p.parseCommand()
+	case unicode.IsDigit(r) || r == '.' && p.pos+1 < len(p.input) && unicode.IsDigit(p.input[p.pos+1]):
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		return "<mn>" + string(p.input[start:p.pos]) + "</mn>", false, nil
+	case unicode.IsLetter(r):
+		p.pos++
+		return "<mi>" + template.HTMLEscapeString(string(r)) + "</mi>", false, nil
+	case strings.ContainsRune("+-=<>()[]|/,;:!.*?", r):
+		p.pos++
+		op := string(r)
+		if r == '-' {
+			op = "−"
+		}
+		return "<mo>" + template.HTMLEscapeString(op) + "</mo>", false, nil
+	case r == '&' || r == '#' || r == '%' || r == '$' || r == '~':
+		return "", false, fmt.Errorf("unsupported character %q", r)
+	}
+	return "", false, fmt.Errorf("unexpected character %q", r)
+}
+
+func (p *mathParser) parseCommand() (string, bool, error) {
+	name := p.readCommand()
+
+	if symbol, ok := mathIdentifiers[name]; ok {
+		return "<mi>" + symbol + "</mi>", false, nil
+	}
+	if symbol, ok := mathOperators[name]; ok {
+		return "<mo>" + template.HTMLEscapeString(symbol) + "</mo>", false, nil
+	}
+	if symbol, ok := mathLargeOperators[name]; ok {
+		if name == "lim" {
+			return "<mo movablelimits=\"true\">lim</mo>", true, nil
+		}
+		return "<mo largeop=\"true\">" + symbol + "</mo>", true, nil
+	}
+	if mathFunctions[name] {
+		return "<mi mathvariant=\"normal\">" + name + "</mi><mo>⁡</mo>", false, nil
+	}
+	if width, ok := mathSpaces[name]; ok {
+		return fmt.Sprintf("<mspace width=\"%s\"/>", width), false, nil
+	}
+	if accent, ok := mathAccents[name]; ok {
+		arg, err := p.parseArgument()
+		if err != nil {
+			return "", false, fmt.Errorf("\\%s: %w", name, err)
+		}
+		return fmt.Sprintf("<mover accent=\"true\">%s<mo>%s</mo></mover>", arg, accent), false, nil
+	}
+	if variant, ok := mathVariants[name]; ok {
+		text, err := p.readBraced()
+		if err != nil {
+			return "", false, fmt.Errorf("\\%s: %w", name, err)
+		}
+		return fmt.Sprintf("<mi mathvariant=\"%s\">%s</mi>", variant, template.HTMLEscapeString(strings.TrimSpace(text))), false, nil
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		numerator, err := p.parseArgument()
+		if err != nil {
+			return "", false, fmt.Errorf("\\frac: %w", err)
+		}
+		denominator, err := p.parseArgument()
+		if err != nil {
+			return "", false, fmt.Errorf("\\frac: %w", err)
+		}
+		return "<mfrac>" + numerator + denominator + "</mfrac>", false, nil
+	case "sqrt":
+		p.skipSpace()
+		if p.peek() == '[' {
+			p.pos++
+			index, err := p.parseSequence(']')
+			if err != nil {
+				return "", false, fmt.Errorf("\\sqrt: %w", err)
+			}
+			radicand, err := p.parseArgument()
+			if err != nil {
+				return "", false, fmt.Errorf("\\sqrt: %w", err)
+			}
+			return "<mroot>" + radicand + wrapRow(index) + "</mroot>", false, nil
+		}
+		radicand, err := p.parseArgument()
+		if err != nil {
+			return "", false, fmt.Errorf("\\sqrt: %w", err)
+		}
+		return "<msqrt>" + radicand + "</msqrt>", false, nil
+	case "text", "textrm", "mbox":
+		text, err := p.readBraced()
+		if err != nil {
+			return "", false, fmt.Errorf("\\%s: %w", name, err)
+		}
+		return "<mtext>" + template.HTMLEscapeString(text) + "</mtext>", false, nil
+	case "operatorname":
+		text, err := p.readBraced()
+		if err != nil {
+			return "", false, fmt.Errorf("\\operatorname: %w", err)
+		}
+		return "<mi mathvariant=\"normal\">" + template.HTMLEscapeString(text) + "</mi><mo>⁡</mo>", false, nil
+	case "left":
+		return p.parseDelimited()
+	}
+
+	return "", false, fmt.Errorf("unsupported command \\%s", name)
+}
+
+// parseDelimited parses \left( ... \right) into stretchy fences.
+func (p *mathParser) parseDelimited() (string, bool, error) {
+	open, err := p.readDelimiter()
+	if err != nil {
+		return "", false, fmt.Errorf("\\left: %w", err)
+	}
+
+	var body strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			return "", false, fmt.Errorf("\\left without matching \\right")
+		}
+		if p.peek() == '\\' && p.peekCommand() == "right" {
+			p.readCommand()
+			break
+		}
+		atom, err := p.parseScripted()
+		if err != nil {
+			return "", false, err
+		}
+		body.WriteString(atom)
+	}
+
+	closing, err := p.readDelimiter()
+	if err != nil {
+		return "", false, fmt.Errorf("\\right: %w", err)
+	}
+
+	return fmt.Sprintf("<mrow>%s%s%s</mrow>", fence(open), body.String(), fence(closing)), false, nil
+}
+
+func (p *mathParser) readDelimiter() (string, error) {
+	p.skipSpace()
+	r := p.peek()
+	switch {
+	case r == '.':
+		p.pos++
+		return "", nil
+	case strings.ContainsRune("()[]|", r):
+		p.pos++
+		return string(r), nil
+	case r == '\\':
+		name := p.readCommand()
+		if symbol, ok := mathOperators[name]; ok {
+			return symbol, nil
+		}
+		return "", fmt.Errorf("unsupported delimiter \\%s", name)
+	}
+	return "", fmt.Errorf("missing delimiter")
+}
+
+func fence(delimiter string) string {
+	if delimiter == "" {
+		return ""
+	}
+	return "<mo fence=\"true\" stretchy=\"true\">" + template.HTMLEscapeString(delimiter) + "</mo>"
+}
+
+func wrapRow(content string) string {
+	return "<mrow>" + content + "</mrow>"
+}
+
+// displayMath is $$…$$ written within a paragraph. It's rendered as display
+// math but stays inline, since a <div> can't go inside a <p>.
+type displayMath struct {
+	ast.Leaf
+}
+
+// inlineMath replaces the parser's $ handler. $$…$$ is matched before $…$,
+// and $…$ needs no space just inside either $ and no digit straight after
+// the closing one, so prices such as "$5 and $10" stay text.
+func inlineMath(p *parser.Parser, data []byte, offset int) (int, ast.Node) {
+	// The second $ of an unmatched $$ isn't an opening
+	if offset > 0 && data[offset-1] == '$' {
+		return 0, nil
+	}
+	data = data[offset:]
+
+	if bytes.HasPrefix(data, []byte("$$")) {
+		end := bytes.Index(data[2:], []byte("$$"))
+		if end <= 0 {
+			return 0, nil
+		}
+		math := &displayMath{}
+		math.Literal = data[2 : end+2]
+		return end + 4, math
+	}
+
+	if len(data) < 3 || parser.IsSpace(data[1]) {
+		return 0, nil
+	}
+	end := bytes.IndexByte(data[1:], '$') + 1
+	if end <= 1 || parser.IsSpace(data[end-1]) {
+		return 0, nil
+	}
+	if end+1 < len(data) && data[end+1] >= '0' && data[end+1] <= '9' {
+		return 0, nil
+	}
+
+	math := &ast.Math{}
+	math.Literal = data[1:end]
+	return end + 1, math
+}
+
+// mathHook renders $inline$ and $$block$$ math as MathML, recording the first
+// translation error so markdownToHTML can report it.
+func mathHook(errs *[]error) html.RenderNodeFunc {
+	return func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+		var source []byte
+		var display, block bool
+		switch n := node.(type) {
+		case *ast.Math:
+			source = n.Literal
+		case *displayMath:
+			source, display = n.Literal, true
+		case *ast.MathBlock:
+			if !entering {
+				return ast.GoToNext, true
+			}
+			source, display, block = n.Literal, true, true
+		default:
+			return ast.GoToNext, false
+		}
+
+		mathML, err := latexToMathML(string(source), display)
+		if err != nil {
+			*errs = append(*errs, err)
+			return ast.GoToNext, true
+		}
+		if block {
+			io.WriteString(w, "<div class=\"math-block\">"+mathML+"</div>\n")
+		} else {
+			io.WriteString(w, mathML)
+		}
+		return ast.GoToNext, true
+	}
+}
@@ -0,0 +1,206 @@
+package site
+
+import (
+	"strings"
+)
+
+// preservedElements keep their contents byte for byte when minifying HTML.
+var preservedElements = map[string]bool{
+	"pre":      true,
+	"textarea": true,
+	"script":   true,
+	"style":    true,
+}
+
+// blockElements are elements whose surrounding whitespace never renders, so
+// the minifier can drop it entirely rather than collapsing it to a space.
+var blockElements = map[string]bool{
+	"!doctype": true, "html": true, "head": true, "body": true, "meta": true,
+	"link": true, "title": true, "script": true, "style": true, "nav": true,
+	"header": true, "footer": true, "main": true, "section": true, "article": true,
+	"aside": true, "div": true, "p": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "ul": true, "ol": true, "li": true,
+	"dl": true, "dt": true, "dd": true, "table": true, "thead": true, "tbody": true,
+	"tr": true, "th": true, "td": true, "blockquote": true, "figure": true,
+	"figcaption": true, "details": true, "summary": true, "hr": true, "br": true, "pre": true,
+}
+
+// minifyHTML strips comments and template indentation from a page. Runs of
+// whitespace collapse to a single space, and disappear next to block-level
+// tags where they would never render.
+func minifyHTML(content string) string {
+	var out strings.Builder
+	pos := 0
+	lastTag := "!doctype"
+	var pending strings.Builder
+
+	// flushText writes the text seen since the last tag, now that the tag
+	// that follows it is known.
+	flushText := func(nextTag string) {
+		text := collapseWhitespace(pending.String())
+		pending.Reset()
+		if blockElements[lastTag] {
+			text = strings.TrimLeft(text, " ")
+		}
+		if blockElements[nextTag] {
+			text = strings.TrimRight(text, " ")
+		}
+		out.WriteString(text)
+	}
+
+	for pos < len(content) {
+		start := strings.IndexByte(content[pos:], '<')
+		if start < 0 {
+			pending.WriteString(content[pos:])
+			break
+		}
+		pending.WriteString(content[pos : pos+start])
+		pos += start
+
+		if strings.HasPrefix(content[pos:], "<!--") {
+			end := strings.Index(content[pos:], "-->")
+			if end < 0 {
+				pending.WriteString(content[pos:])
+				break
+			}
+			pos += end + len("-->")
+			continue
+		}
+
+		end := tagEnd(content, pos)
+		tag := content[pos:end]
+		name := tagName(tag)
+		flushText(name)
+		out.WriteString(tag)
+		lastTag = name
+		pos = end
+
+		// Keep preformatted and script content as written
+		if preservedElements[name] && !strings.HasPrefix(tag, "</") {
+			closing := "</" + name
+			closeAt := strings.Index(strings.ToLower(content[pos:]), closing)
+			if closeAt < 0 {
+				out.WriteString(content[pos:])
+				return out.String()
+			}
+			inner := content[pos : pos+closeAt]
+			if name == "style" {
+				inner = minifyCSS(inner)
+			}
+			out.WriteString(inner)
+			pos += closeAt
+		}
+	}
+	flushText("")
+
+	return strings.TrimSpace(out.String())
+}
+
+// tagEnd returns the index just past the tag starting at pos, skipping over
+// quoted attribute values.
+func tagEnd(content string, pos int) int {
+	var quote byte
+	for i := pos + 1; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(content)
+}
+
+func tagName(tag string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(tag, "<"), "/")
+	if i := strings.IndexAny(name, " \t\r\n/>"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+func collapseWhitespace(text string) string {
+	var out strings.Builder
+	space := false
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			out.WriteByte(' ')
+			space = false
+		}
+		out.WriteRune(r)
+	}
+	if space {
+		out.WriteByte(' ')
+	}
+	return out.String()
+}
+
+// minifyCSS strips comments and the whitespace around punctuation that
+// doesn't affect how a stylesheet is parsed. Strings are left alone.
+func minifyCSS(css string) string {
+	var out []byte
+	space := false
+
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				i = len(css)
+			} else {
+				i += end + 3
+			}
+			space = true
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(css) && css[end] != c {
+				if css[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(css) {
+				end = len(css) - 1
+			}
+			out = appendCSSSpace(out, space, c)
+			space = false
+			out = append(out, css[i:end+1]...)
+			i = end
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = true
+		default:
+			// The last declaration in a block doesn't need its semicolon
+			if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+				out = out[:len(out)-1]
+			}
+			out = appendCSSSpace(out, space, c)
+			space = false
+			out = append(out, c)
+		}
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// appendCSSSpace adds a collapsed space before next unless either side of
+// it is punctuation that makes the space redundant.
+func appendCSSSpace(out []byte, space bool, next byte) []byte {
+	if !space || len(out) == 0 {
+		return out
+	}
+	prev := out[len(out)-1]
+	if strings.IndexByte("{};:,>", prev) >= 0 || strings.IndexByte("{};,>)", next) >= 0 {
+		return out
+	}
+	return append(out, ' ')
+}
@@ -0,0 +1,41 @@
+package site
+
+import "testing"
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "block whitespace",
+			in:   "<main>\n  <p>\n    Hello   <em>there</em>\n  </p>\n</main>",
+			want: "<main><p>Hello <em>there</em></p></main>",
+		},
+		{
+			name: "inline math",
+			in:   "<p>where <math display=\"inline\"><mi>x</mi></math> is positive</p>",
+			want: "<p>where <math display=\"inline\"><mi>x</mi></math> is positive</p>",
+		},
+		{
+			name: "inline svg",
+			in:   "<p>an icon <svg width=\"8\"><g><path d=\"M0 0\"/></g></svg> here</p>",
+			want: "<p>an icon <svg width=\"8\"><g><path d=\"M0 0\"/></g></svg> here</p>",
+		},
+		{
+			name: "comments",
+			in:   "<div>\n  <!-- a note -->\n  <!-- notes:start --><p>Post</p>\n  <!-- notes:end -->\n</div>",
+			want: "<div><p>Post</p></div>",
+		},
+		{
+			name: "preformatted",
+			in:   "<pre>  keep\n    this  </pre>",
+			want: "<pre>  keep\n    this  </pre>",
+		},
+	}
+
+	for _, test := range tests {
+		if got := minifyHTML(test.in); got != test.want {
+			t.Errorf("%s: minifyHTML(%q) = %q, want %q", test.name, test.in, got, test.want)
+		}
+	}
+}
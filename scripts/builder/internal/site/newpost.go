@@ -0,0 +1,239 @@
+package site
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NewPostOptions describes content to scaffold from an archetype.
+type NewPostOptions struct {
+	Title       string
+	Description string
+	Tags        string
+	Section     string
+	Type        string
+	Force       bool
+
+	// Slug defaults to one made from the title
+	Slug string
+
+	// Used by link posts
+	URL   string
+	Via   string
+	Quote string
+
+	// Date defaults to today
+	Date time.Time
+}
+
+// archetypeDirs is where each content type lives when it isn't a post.
+var archetypeDirs = map[string]string{
+	"book": "library",
+}
+
+// archetypeData is passed to archetype templates.
+type archetypeData struct {
+	Title       string
+	Description string
+	Tags        string
+	Section     string
+	Type        string
+	Slug        string
+	Date        string
+	URL         string
+	Via         string
+	Quote       string
+}
+
+// quoteYAML quotes a value as a YAML double-quoted scalar.
+func quoteYAML(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// unquoteYAML reads a frontmatter value, undoing YAML quoting. Values that
+// aren't cleanly quoted just have their quotes trimmed.
+func unquoteYAML(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return strings.Trim(value, `"'`)
+}
+
+// frontmatterList reads a block list from the frontmatter parser, whose
+// items are left quoted one per line.
+func frontmatterList(value string) []string {
+	var items []string
+	for _, line := range strings.Split(value, "\n") {
+		if item := unquoteYAML(strings.TrimSpace(line)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// CreateNewPost scaffolds a new content file from archetypes/<type>.md and
+// returns its path. Existing files are only replaced when opts.Force is set.
+func (g *Generator) CreateNewPost(opts NewPostOptions) (string, error) {
+	if opts.Type == "" {
+		opts.Type = "note"
+	}
+	if strings.TrimSpace(opts.Title) == "" {
+		return "", fmt.Errorf("title is required")
+	}
+	if opts.Type == "link" && strings.TrimSpace(opts.URL) == "" {
+		return "", fmt.Errorf("url is required for link posts (use -url)")
+	}
+	slug := opts.Slug
+	if slug == "" {
+		slug = g.slugify(opts.Title)
+	}
+	if slug == "" {
+		return "", fmt.Errorf("title %q has no characters usable in a filename", opts.Title)
+	}
+
+	archetypePath := filepath.Join(g.rootDir, "archetypes", opts.Type+".md")
+	archetype, err := os.ReadFile(archetypePath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("unknown content type %q (no archetypes/%s.md)", opts.Type, opts.Type)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read archetype: %w", err)
+	}
+
+	tmpl, err := template.New(opts.Type).Funcs(template.FuncMap{"yaml": quoteYAML}).Parse(string(archetype))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse archetypes/%s.md: %w", opts.Type, err)
+	}
+
+	dir := archetypeDirs[opts.Type]
+	if dir == "" {
+		dir = "posts"
+	}
+	if err := os.MkdirAll(filepath.Join(g.rootDir, dir), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s directory: %w", dir, err)
+	}
+
+	postPath := filepath.Join(g.rootDir, dir, slug+".md")
+	if _, err := os.Stat(postPath); err == nil && !opts.Force {
+		return "", fmt.Errorf("%s/%s.md already exists (use -force to overwrite it)", dir, slug)
+	}
+
+	date := opts.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, archetypeData{
+		Title:       opts.Title,
+		Description: opts.Description,
+		Tags:        opts.Tags,
+		Section:     opts.Section,
+		Type:        opts.Type,
+		Slug:        slug,
+		Date:        g.formatDate(date),
+		URL:         opts.URL,
+		Via:         opts.Via,
+		Quote:       opts.Quote,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute archetypes/%s.md: %w", opts.Type, err)
+	}
+
+	if err := os.WriteFile(postPath, []byte(content.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write post file: %w", err)
+	}
+
+	fmt.Printf("Created new %s: %s\n", opts.Type, postPath)
+	return postPath, nil
+}
+
+// PromptNewPost asks for any fields missing from opts.
+func PromptNewPost(opts *NewPostOptions, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	ask := func(label, fallback string, value *string) error {
+		if *value != "" {
+			return nil
+		}
+		if fallback != "" {
+			fmt.Fprintf(out, "%s [%s]: ", label, fallback)
+		} else {
+			fmt.Fprintf(out, "%s: ", label)
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		*value = strings.TrimSpace(line)
+		if *value == "" {
+			*value = fallback
+		}
+		return nil
+	}
+
+	type field struct {
+		label    string
+		fallback string
+		value    *string
+	}
+	fields := []field{
+		{"Type (note, essay, book, link)", "note", &opts.Type},
+		{"Title", "", &opts.Title},
+		{"Description", "", &opts.Description},
+		{"Tags (comma-separated)", "", &opts.Tags},
+		{"Section", "", &opts.Section},
+	}
+	// Link posts also need the page they link to
+	linkFields := []field{
+		{"URL", "", &opts.URL},
+		{"Via (a name or URL)", "", &opts.Via},
+		{"Quote", "", &opts.Quote},
+	}
+
+	for _, field := range fields {
+		if err := ask(field.label, field.fallback, field.value); err != nil {
+			return err
+		}
+	}
+	if opts.Type == "link" {
+		for _, field := range linkFields {
+			if err := ask(field.label, field.fallback, field.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// OpenInEditor opens a file in $VISUAL or $EDITOR, falling back to vi.
+func OpenInEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Editors are often configured with arguments, such as "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,35 @@
+package site
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPromptNewLinkPost(t *testing.T) {
+	var opts NewPostOptions
+	in := strings.NewReader("link\nA good read\n\n\n\nhttps://example.com/essay\nSomeone\n\n")
+	if err := PromptNewPost(&opts, in, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Type != "link" || opts.Title != "A good read" || opts.URL != "https://example.com/essay" || opts.Via != "Someone" || opts.Quote != "" {
+		t.Errorf("prompted options = %+v", opts)
+	}
+
+	notes := NewPostOptions{Type: "note", Title: "A note"}
+	if err := PromptNewPost(&notes, strings.NewReader("\n\n\n"), io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if notes.URL != "" {
+		t.Errorf("a note was asked for a URL: %+v", notes)
+	}
+}
+
+func TestNewLinkPostNeedsURL(t *testing.T) {
+	g := NewGenerator()
+	g.rootDir = t.TempDir()
+	_, err := g.CreateNewPost(NewPostOptions{Type: "link", Title: "A good read"})
+	if err == nil || err.Error() != "url is required for link posts (use -url)" {
+		t.Errorf("CreateNewPost error = %v, want the missing url reported", err)
+	}
+}
@@ -0,0 +1,450 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PaginationConfig sets how lists of posts are split across pages.
+type PaginationConfig struct {
+	// PageSize is the number of posts on each page; 0 puts them all on one
+	PageSize int `json:"pageSize"`
+
+	// Path is where a list's later pages go, relative to its first page,
+	// using :page for the page number
+	Path string `json:"path"`
+}
+
+const (
+	defaultPageSize       = 10
+	defaultPaginationPath = "/page/:page/"
+)
+
+// The homepage's writing list is generated between these markers.
+const (
+	homepageNotesStart = "<!-- notes:start -->"
+	homepageNotesEnd   = "<!-- notes:end -->"
+)
+
+// postList is a list of posts split across pages: the writing list that
+// starts on the homepage, or a section's or tag's page.
+type postList struct {
+	Title       string
+	Description string
+
+	// URL is the first page's
+	URL string
+
+	// Section and Tag are set on their lists, for the feeds the pages link to
+	Section string
+	Tag     string
+
+	// Posts are newest first
+	Posts []*Post
+}
+
+// paginator is a page of a postList as templates see it.
+type paginator struct {
+	Page       int
+	TotalPages int
+	TotalPosts int
+	PageSize   int
+	First      string
+	Last       string
+	Previous   string
+	Next       string
+	Pages      []paginatorPage
+}
+
+type paginatorPage struct {
+	Number  int
+	URL     string
+	Current bool
+}
+
+// noteRow is a post in a list.
+type noteRow struct {
+	URL         string
+	Date        string
+	Title       string
+	ReadingTime int
+	Description string
+}
+
+const noteRowTemplate = `{{define "note-row"}}
+        <a href="{{.URL}}" class="note-row">
+          <div class="note-header">
+            <time>{{.Date}}</time>
+            <h3>{{.Title}}</h3>
+            <span class="reading-time">{{.ReadingTime}} min read</span>
+          </div>
+          <p>{{.Description}}</p>
+        </a>{{end}}`
+
+const paginationTemplate = `{{define "pagination"}}{{if gt .TotalPages 1}}
+      <nav class="pagination" aria-label="Pages">
+        {{- with .Previous}}
+        <a href="{{.}}" class="pagination-previous" rel="prev">Newer</a>
+        {{- end}}
+        <ol>
+          {{- range .Pages}}
+          <li>{{if .Current}}<span aria-current="page">{{.Number}}</span>{{else}}<a href="{{.URL}}">{{.Number}}</a>{{end}}</li>
+          {{- end}}
+        </ol>
+        {{- with .Next}}
+        <a href="{{.}}" class="pagination-next" rel="next">Older</a>
+        {{- end}}
+      </nav>{{end}}{{end}}`
+
+// newestFirst returns a copy of the posts sorted newest first.
+func newestFirst(posts []*Post) []*Post {
+	sorted := append([]*Post(nil), posts...)
+	sortPostsByDate(sorted)
+	for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	}
+	return sorted
+}
+
+// writingList is the Notes posts the homepage lists.
+func (g *Generator) writingList(posts []*Post) *postList {
+	list := &postList{
+		Title:       "Writing",
+		Description: "Quick jots, thoughts and observations.",
+		URL:         "/",
+		Section:     "Notes",
+	}
+	for _, post := range newestFirst(posts) {
+		if post.Section == "Notes" {
+			list.Posts = append(list.Posts, post)
+		}
+	}
+	return list
+}
+
+// taxonomyLists groups the posts into a list per section and per tag. Tags
+// that differ only in case share a list.
+func (g *Generator) taxonomyLists(posts []*Post) []*postList {
+	var lists []*postList
+	byURL := make(map[string]*postList)
+	add := func(url string, list *postList, post *Post) {
+		if byURL[url] == nil {
+			list.URL = url
+			byURL[url] = list
+			lists = append(lists, list)
+		}
+		// A post tagged both "Go" and "go" is listed once
+		existing := byURL[url]
+		if n := len(existing.Posts); n == 0 || existing.Posts[n-1] != post {
+			existing.Posts = append(existing.Posts, post)
+		}
+	}
+
+	sorted := newestFirst(posts)
+	for _, post := range sorted {
+		if g.slugify(post.Section) != "" {
+			add(g.sectionURL(post.Section), &postList{
+				Title:       post.Section,
+				Description: fmt.Sprintf("%s from %s.", post.Section, g.config.Title),
+				Section:     post.Section,
+			}, post)
+		}
+	}
+	for _, post := range sorted {
+		for _, tag := range splitTags(post.Tags) {
+			if g.slugify(tag) != "" {
+				add(g.tagURL(tag), &postList{
+					Title:       "Posts tagged " + tag,
+					Description: fmt.Sprintf("Posts tagged %s from %s.", tag, g.config.Title),
+					Tag:         tag,
+				}, post)
+			}
+		}
+	}
+	return lists
+}
+
+// pageURL is the URL of a page of a list whose first page is at url.
+func (g *Generator) pageURL(url string, page int) string {
+	if page <= 1 {
+		return url
+	}
+	return strings.TrimSuffix(pageDir(url), "/") + permalink(g.config.Pagination.Path, map[string]string{"page": strconv.Itoa(page)})
+}
+
+// totalPages is how many pages a list of count posts takes, at least one.
+func (g *Generator) totalPages(count int) int {
+	size := g.config.Pagination.PageSize
+	if size <= 0 || count <= size {
+		return 1
+	}
+	return (count + size - 1) / size
+}
+
+// paginate splits a list into its pages.
+func (g *Generator) paginate(list *postList) ([][]*Post, []*paginator) {
+	total := g.totalPages(len(list.Posts))
+	size := len(list.Posts)
+	if total > 1 {
+		size = g.config.Pagination.PageSize
+	}
+
+	var pages [][]*Post
+	var paginators []*paginator
+	for page := 1; page <= total; page++ {
+		start, end := (page-1)*size, page*size
+		if end > len(list.Posts) {
+			end = len(list.Posts)
+		}
+		pages = append(pages, list.Posts[start:end])
+
+		p := &paginator{
+			Page:       page,
+			TotalPages: total,
+			TotalPosts: len(list.Posts),
+			PageSize:   size,
+			First:      g.pageURL(list.URL, 1),
+			Last:       g.pageURL(list.URL, total),
+		}
+		if page > 1 {
+			p.Previous = g.pageURL(list.URL, page-1)
+		}
+		if page < total {
+			p.Next = g.pageURL(list.URL, page+1)
+		}
+		for number := 1; number <= total; number++ {
+			p.Pages = append(p.Pages, paginatorPage{Number: number, URL: g.pageURL(list.URL, number), Current: number == page})
+		}
+		paginators = append(paginators, p)
+	}
+	return pages, paginators
+}
+
+func (g *Generator) noteRows(posts []*Post) []noteRow {
+	var rows []noteRow
+	for _, post := range posts {
+		date := post.Created
+		if date == "" {
+			date = "Unknown date"
+		}
+		rows = append(rows, noteRow{
+			URL:         g.postURL(post),
+			Date:        date,
+			Title:       post.Title,
+			ReadingTime: post.ReadingTime,
+			Description: post.Description,
+		})
+	}
+	return rows
+}
+
+// listURLs are the URLs of every generated list page: the writing list's
+// pages after the homepage, and every page of each section and tag.
+func (g *Generator) listURLs(posts []*Post) []string {
+	var urls []string
+	writing := g.writingList(posts)
+	for page := 2; page <= g.totalPages(len(writing.Posts)); page++ {
+		urls = append(urls, g.pageURL(writing.URL, page))
+	}
+	for _, list := range g.taxonomyLists(posts) {
+		for page := 1; page <= g.totalPages(len(list.Posts)); page++ {
+			urls = append(urls, g.pageURL(list.URL, page))
+		}
+	}
+	return urls
+}
+
+// replaceHomepageNotes renders the first page of the writing list into the
+// homepage.
+func (g *Generator) replaceHomepageNotes(content string, posts []*Post) (string, error) {
+	start := strings.Index(content, homepageNotesStart)
+	end := strings.Index(content, homepageNotesEnd)
+	if start < 0 || end < start {
+		return content, nil
+	}
+
+	pages, paginators := g.paginate(g.writingList(posts))
+	t, err := template.New("homepage-notes").Parse(`
+      <div class="notes-list">
+        {{- range .Rows}}{{template "note-row" .}}{{end}}
+      </div>{{template "pagination" .Paginator}}`)
+	if err == nil {
+		_, err = t.Parse(noteRowTemplate)
+	}
+	if err == nil {
+		_, err = t.Parse(paginationTemplate)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var list strings.Builder
+	err = t.Execute(&list, map[string]interface{}{
+		"Rows":      g.noteRows(pages[0]),
+		"Paginator": paginators[0],
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return content[:start+len(homepageNotesStart)] + list.String() + "\n      " + content[end:], nil
+}
+
+// generateListPages writes the writing list's later pages and every page of
+// each section and tag, and removes pages that no longer exist.
+func (g *Generator) generateListPages(posts []*Post) error {
+	written := make(map[string]bool)
+	write := func(list *postList, firstPage int) error {
+		pages, paginators := g.paginate(list)
+		for i := firstPage - 1; i < len(pages); i++ {
+			htmlContent, err := g.generateListHTML(list, pages[i], paginators[i])
+			if err != nil {
+				return fmt.Errorf("failed to generate page %d of %s: %w", i+1, list.URL, err)
+			}
+			path := g.outputPath(g.pageURL(list.URL, i+1))
+			if err := g.writePage(path, htmlContent); err != nil {
+				return fmt.Errorf("failed to write page %d of %s: %w", i+1, list.URL, err)
+			}
+			written[path] = true
+		}
+		return nil
+	}
+
+	// The homepage is the writing list's first page
+	if err := write(g.writingList(posts), 2); err != nil {
+		return err
+	}
+	lists := g.taxonomyLists(posts)
+	for _, list := range lists {
+		if err := write(list, 1); err != nil {
+			return err
+		}
+	}
+
+	// Drop pages past the end of a list, and the pages of sections and tags
+	// no post uses any more
+	var stale []string
+	for _, url := range []string{"/", permalink(g.config.Permalinks.Sections, map[string]string{"slug": "*"}), permalink(g.config.Permalinks.Tags, map[string]string{"slug": "*"})} {
+		if url != "/" {
+			stale = append(stale, g.outputPath(url))
+		}
+		later := strings.TrimSuffix(pageDir(url), "/") + permalink(g.config.Pagination.Path, map[string]string{"page": "*"})
+		stale = append(stale, g.outputPath(later))
+	}
+	for _, pattern := range stale {
+		existing, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, path := range existing {
+			if written[path] {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove stale %s: %w", path, err)
+			}
+			// Only succeeds once the directories are empty
+			os.Remove(filepath.Dir(path))
+			os.Remove(filepath.Dir(filepath.Dir(path)))
+		}
+	}
+
+	fmt.Printf("Generated list pages for %d sections and tags\n", len(lists))
+	return nil
+}
+
+func (g *Generator) generateListHTML(list *postList, posts []*Post, page *paginator) (string, error) {
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
+  <meta name="description" content="{{.Description}}">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
+  <title>{{.Meta.Title}}</title>
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
+  {{- with .Paginator.Previous}}
+  <link rel="prev" href="{{.}}">
+  {{- end}}
+  {{- with .Paginator.Next}}
+  <link rel="next" href="{{.}}">
+  {{- end}}
+</head>
+<body>
+  <nav class="navbar">
+    <div class="container nav-content">
+      <a href="/" class="logo">J</a>
+      <div class="nav-links">
+        <a href="/index.html#notes">Writing</a>
+        <a href="/about.html">About</a>
+      </div>
+    </div>
+  </nav>
+  <div class="container">
+    <header class="post-heading">
+      <h1>{{.Title}}</h1>
+      <p class="post-description">{{.Description}}</p>
+    </header>
+    <main>
+      <div class="notes-list">
+        {{- range .Rows}}{{template "note-row" .}}{{end}}
+      </div>
+      {{- template "pagination" .Paginator}}
+      <div class="back-button-container">
+        <a href="/" class="back-button">Back to home</a>
+      </div>
+    </main>
+  </div>
+</body>
+</html>`
+
+	t, err := template.New("list").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(noteRowTemplate)
+	}
+	if err == nil {
+		_, err = t.Parse(paginationTemplate)
+	}
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var tags []string
+	if list.Tag != "" {
+		tags = []string{list.Tag}
+	}
+
+	title := list.Title
+	if page.Page > 1 {
+		title = fmt.Sprintf("%s (page %d)", title, page.Page)
+	}
+	meta, err := g.listMeta(title, list.Description, g.pageURL(list.URL, page.Page), g.pageFeeds(list.Section, tags))
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":        meta,
+		"Title":       list.Title,
+		"Description": list.Description,
+		"Rows":        g.noteRows(posts),
+		"Paginator":   page,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,110 @@
+package site
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PermalinkConfig sets the URL pattern of each kind of generated page.
+// Patterns can use :slug, :section, :year, :month and :day. Patterns ending
+// in / are written as <path>/index.html.
+type PermalinkConfig struct {
+	Posts   string `json:"posts"`
+	Library string `json:"library"`
+	Series  string `json:"series"`
+	Links   string `json:"links"`
+	Reading string `json:"reading"`
+
+	// Tags and Sections are the pages listing each tag's and section's posts
+	Tags     string `json:"tags"`
+	Sections string `json:"sections"`
+
+	// Archive lists every post by month, and Years each year's posts
+	Archive string `json:"archive"`
+	Years   string `json:"years"`
+}
+
+const (
+	legacyPostPattern    = "/posts/:slug.html"
+	legacyLibraryPattern = "/library/:slug.html"
+	legacySeriesPattern  = "/series/:slug.html"
+	legacyLinksPattern   = "/links.html"
+	legacyReadingPattern = "/reading.html"
+
+	defaultTagsPattern     = "/tags/:slug/"
+	defaultSectionsPattern = "/sections/:slug/"
+)
+
+// libraryIndexURL is the generated index of library/, served as library/index.html.
+const libraryIndexURL = "/library/"
+
+var permalinkTokenPattern = regexp.MustCompile(`:(slug|section|year|month|day|page)`)
+
+// permalink expands a pattern. Segments left empty by missing values are
+// dropped rather than producing a double slash.
+func permalink(pattern string, vars map[string]string) string {
+	url := permalinkTokenPattern.ReplaceAllStringFunc(pattern, func(token string) string {
+		return vars[token[1:]]
+	})
+	for strings.Contains(url, "//") {
+		url = strings.ReplaceAll(url, "//", "/")
+	}
+	return "/" + strings.TrimPrefix(url, "/")
+}
+
+func (g *Generator) postVars(post *Post, slug string) map[string]string {
+	vars := map[string]string{
+		"slug":    slug,
+		"section": g.slugify(post.Section),
+	}
+	if created, ok := parseDate(post.Created); ok {
+		vars["year"] = created.Format("2006")
+		vars["month"] = created.Format("01")
+		vars["day"] = created.Format("02")
+	}
+	return vars
+}
+
+func (g *Generator) postURL(post *Post) string {
+	return permalink(g.config.Permalinks.Posts, g.postVars(post, post.Slug))
+}
+
+func (g *Generator) libraryURL(item *LibraryItem) string {
+	// Legacy HTML pages are served from where they are
+	if !strings.HasSuffix(item.Filename, ".md") {
+		return "/library/" + item.Filename
+	}
+	return permalink(g.config.Permalinks.Library, map[string]string{"slug": item.ID})
+}
+
+func (g *Generator) seriesURL(series *Series) string {
+	return permalink(g.config.Permalinks.Series, map[string]string{"slug": series.Slug})
+}
+
+func (g *Generator) linksURL() string {
+	return permalink(g.config.Permalinks.Links, nil)
+}
+
+func (g *Generator) readingURL() string {
+	return permalink(g.config.Permalinks.Reading, nil)
+}
+
+func (g *Generator) tagURL(tag string) string {
+	return permalink(g.config.Permalinks.Tags, map[string]string{"slug": g.slugify(tag)})
+}
+
+func (g *Generator) sectionURL(section string) string {
+	return permalink(g.config.Permalinks.Sections, map[string]string{"slug": g.slugify(section)})
+}
+
+// outputPath is the file a page with the given URL is written to.
+func (g *Generator) outputPath(url string) string {
+	path := strings.TrimPrefix(url, "/")
+	if path == "" || strings.HasSuffix(path, "/") {
+		path += "index.html"
+	} else if !strings.HasSuffix(path, ".html") {
+		path += "/index.html"
+	}
+	return filepath.Join(g.rootDir, filepath.FromSlash(path))
+}
@@ -0,0 +1,232 @@
+package site
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	previewWidth  = 1200
+	previewHeight = 630
+	previewMargin = 80
+
+	// previewVersion is part of the input hash; bump it when the layout changes
+	// so existing images are regenerated.
+	previewVersion = "1"
+	previewHashKey = "jjc-preview-hash"
+)
+
+var (
+	previewBackground = color.RGBA{0xf8, 0xf8, 0xf8, 0xff}
+	previewText       = color.RGBA{0x1a, 0x1a, 0x1a, 0xff}
+	previewMuted      = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	previewAccent     = color.RGBA{0x1a, 0x1a, 0x1a, 0xff}
+)
+
+// previewPath is the site-relative path of the generated social image for a post.
+func (g *Generator) previewPath(post *Post) string {
+	return "posts/" + post.Slug + ".png"
+}
+
+func (g *Generator) previewHash(post *Post) string {
+	sum := sha256.New()
+	for _, input := range []string{previewVersion, g.config.Title, post.Title, post.Description, post.Created} {
+		sum.Write([]byte(input))
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// generatePreviewImage renders the 1200x630 Open Graph image for a post
+// without a cover. Images whose inputs haven't changed are left alone.
+func (g *Generator) generatePreviewImage(post *Post) error {
+	imagePath := filepath.Join(g.rootDir, filepath.FromSlash(g.previewPath(post)))
+	hash := g.previewHash(post)
+
+	if existing, err := os.ReadFile(imagePath); err == nil && pngText(existing, previewHashKey) == hash {
+		return nil
+	}
+
+	img, err := g.renderPreview(post)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("failed to encode preview image: %w", err)
+	}
+
+	content, err := addPNGText(buf.Bytes(), previewHashKey, hash)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(imagePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write preview image: %w", err)
+	}
+
+	fmt.Printf("Generated preview image: %s\n", imagePath)
+	return nil
+}
+
+func (g *Generator) renderPreview(post *Post) (image.Image, error) {
+	titleFace, err := loadFace(gobold.TTF, 64)
+	if err != nil {
+		return nil, err
+	}
+	bodyFace, err := loadFace(goregular.TTF, 32)
+	if err != nil {
+		return nil, err
+	}
+	footerFace, err := loadFace(gobold.TTF, 28)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, previewWidth, previewHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(previewBackground), image.Point{}, draw.Src)
+
+	// Accent bar along the left edge
+	draw.Draw(img, image.Rect(0, 0, 16, previewHeight), image.NewUniform(previewAccent), image.Point{}, draw.Src)
+
+	textWidth := previewWidth - 2*previewMargin
+	y := previewMargin + 64
+
+	for _, line := range wrapText(titleFace, post.Title, textWidth, 3) {
+		drawText(img, titleFace, previewText, previewMargin, y, line)
+		y += 78
+	}
+
+	y += 24
+	for _, line := range wrapText(bodyFace, post.Description, textWidth, 3) {
+		drawText(img, bodyFace, previewMuted, previewMargin, y, line)
+		y += 44
+	}
+
+	footerY := previewHeight - previewMargin
+	drawText(img, footerFace, previewText, previewMargin, footerY, g.config.Title)
+	if post.Created != "" {
+		dateWidth := font.MeasureString(footerFace, post.Created).Ceil()
+		drawText(img, footerFace, previewMuted, previewWidth-previewMargin-dateWidth, footerY, post.Created)
+	}
+
+	return img, nil
+}
+
+func loadFace(ttf []byte, size float64) (font.Face, error) {
+	parsed, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded font: %w", err)
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded font: %w", err)
+	}
+	return face, nil
+}
+
+func drawText(img draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
+
+// wrapText splits text into lines no wider than maxWidth, truncating with an
+// ellipsis after maxLines.
+func wrapText(face font.Face, text string, maxWidth, maxLines int) []string {
+	var lines []string
+	var current string
+
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if current != "" && font.MeasureString(face, candidate).Ceil() > maxWidth {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current = candidate
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1]
+		for last != "" && font.MeasureString(face, last+"…").Ceil() > maxWidth {
+			last = last[:strings.LastIndex(last, " ")+1]
+			last = strings.TrimSpace(last)
+		}
+		lines[maxLines-1] = last + "…"
+	}
+
+	return lines
+}
+
+// addPNGText inserts a tEXt chunk directly after the IHDR chunk of an encoded PNG.
+func addPNGText(data []byte, key, value string) ([]byte, error) {
+	// 8 byte signature + IHDR (4 length + 4 type + 13 data + 4 CRC)
+	const ihdrEnd = 8 + 25
+	if len(data) < ihdrEnd {
+		return nil, fmt.Errorf("invalid PNG data")
+	}
+
+	chunkData := append([]byte(key+"\x00"), value...)
+	chunk := make([]byte, 0, len(chunkData)+12)
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(chunkData)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, chunkData...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	result := make([]byte, 0, len(data)+len(chunk))
+	result = append(result, data[:ihdrEnd]...)
+	result = append(result, chunk...)
+	result = append(result, data[ihdrEnd:]...)
+	return result, nil
+}
+
+// pngText returns the value of the tEXt chunk with the given key, or "".
+func pngText(data []byte, key string) string {
+	offset := 8
+	for offset+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		chunkType := string(data[offset+4 : offset+8])
+		start := offset + 8
+		end := start + length
+		if end > len(data) || chunkType == "IDAT" {
+			return ""
+		}
+		if chunkType == "tEXt" {
+			if k, v, ok := strings.Cut(string(data[start:end]), "\x00"); ok && k == key {
+				return v
+			}
+		}
+		offset = end + 4
+	}
+	return ""
+}
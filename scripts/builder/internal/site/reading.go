@@ -0,0 +1,540 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reading statuses a library item can have. Items without one are treated
+// as read.
+const (
+	statusReading   = "reading"
+	statusRead      = "read"
+	statusAbandoned = "abandoned"
+)
+
+// topTagCount is how many tags the reading stats page lists.
+const topTagCount = 10
+
+// bookEntry is a library item as shown in the library index.
+type bookEntry struct {
+	Title  string
+	Author string
+	URL    string
+	Cover  *bookCover
+	Stars  string
+}
+
+// bookYear is the books finished in one year.
+type bookYear struct {
+	Year  string
+	Books []bookEntry
+}
+
+// readingDetails is the reading log shown on a library item's page.
+type readingDetails struct {
+	Status   string
+	Stars    string
+	Rating   string
+	Started  string
+	Finished string
+	ISBN     string
+}
+
+// itemRating returns an item's rating, if it has a valid one.
+func itemRating(item *LibraryItem) (float64, bool) {
+	if item.Rating == "" {
+		return 0, false
+	}
+	rating, err := strconv.ParseFloat(item.Rating, 64)
+	if err != nil || rating < 0 || rating > 5 {
+		return 0, false
+	}
+	return rating, true
+}
+
+// ratingStars draws a rating out of five, rounded to the nearest half.
+func ratingStars(rating float64) string {
+	halves := int(rating*2 + 0.5)
+	stars := strings.Repeat("★", halves/2)
+	if halves%2 == 1 {
+		stars += "½"
+	}
+	return stars + strings.Repeat("☆", 5-(halves+1)/2)
+}
+
+// isRead reports whether an item counts as a finished book.
+func isRead(item *LibraryItem) bool {
+	return item.Status == "" || item.Status == statusRead
+}
+
+// readDate is when an item was finished, falling back to when it was
+// written about for items that don't record it.
+func readDate(item *LibraryItem) (time.Time, bool) {
+	if !isRead(item) {
+		return time.Time{}, false
+	}
+	if date, ok := parseDate(item.Finished); ok {
+		return date, true
+	}
+	return parseDate(item.Created)
+}
+
+func (g *Generator) readingDetailsFor(item *LibraryItem) *readingDetails {
+	details := &readingDetails{
+		Status:   item.Status,
+		Started:  item.Started,
+		Finished: item.Finished,
+		ISBN:     item.ISBN,
+	}
+	if rating, ok := itemRating(item); ok {
+		details.Stars = ratingStars(rating)
+		details.Rating = strconv.FormatFloat(rating, 'f', -1, 64)
+	}
+	if *details == (readingDetails{}) {
+		return nil
+	}
+	return details
+}
+
+func (g *Generator) bookEntryFor(item *LibraryItem) bookEntry {
+	entry := bookEntry{
+		Title:  item.Title,
+		Author: item.Author,
+		URL:    g.libraryURL(item),
+		Cover:  g.covers[item.ID],
+	}
+	if rating, ok := itemRating(item); ok {
+		entry.Stars = ratingStars(rating)
+	}
+	return entry
+}
+
+// lintLibrary reports reading log fields that can't be used.
+func (g *Generator) lintLibrary(items []*LibraryItem) []string {
+	var problems []string
+	for _, item := range items {
+		source := "library/" + item.Filename
+		switch item.Status {
+		case "", statusReading, statusRead, statusAbandoned:
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown status %q (use reading, read or abandoned)", source, item.Status))
+		}
+		if _, ok := itemRating(item); item.Rating != "" && !ok {
+			problems = append(problems, fmt.Sprintf("%s: rating %q must be a number from 0 to 5", source, item.Rating))
+		}
+
+		started, startedOK := parseDate(item.Started)
+		finished, finishedOK := parseDate(item.Finished)
+		if item.Started != "" && !startedOK {
+			problems = append(problems, fmt.Sprintf("%s: started %q is not a date", source, item.Started))
+		}
+		if item.Finished != "" && !finishedOK {
+			problems = append(problems, fmt.Sprintf("%s: finished %q is not a date", source, item.Finished))
+		}
+		if startedOK && finishedOK && finished.Before(started) {
+			problems = append(problems, fmt.Sprintf("%s: finished %s is before started %s", source, item.Finished, item.Started))
+		}
+		if item.Status == statusReading && item.Finished != "" {
+			problems = append(problems, fmt.Sprintf("%s: status is reading but finished is set", source))
+		}
+
+		if item.ISBN != "" && !validISBN(item.ISBN) {
+			problems = append(problems, fmt.Sprintf("%s: isbn %q has an invalid check digit", source, item.ISBN))
+		}
+	}
+	return problems
+}
+
+// validISBN checks the length and check digit of an ISBN-10 or ISBN-13,
+// ignoring hyphens and spaces.
+func validISBN(isbn string) bool {
+	digits := strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(isbn))
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, c := range digits {
+			var d int
+			switch {
+			case c >= '0' && c <= '9':
+				d = int(c - '0')
+			case c == 'X' && i == 9:
+				d = 10
+			default:
+				return false
+			}
+			sum += d * (10 - i)
+		}
+		return sum%11 == 0
+	case 13:
+		sum := 0
+		for i, c := range digits {
+			if c < '0' || c > '9' {
+				return false
+			}
+			d := int(c - '0')
+			if i%2 == 1 {
+				d *= 3
+			}
+			sum += d
+		}
+		return sum%10 == 0
+	}
+	return false
+}
+
+// bookTemplate is the card for a book in the library grids and expects a
+// bookEntry.
+const bookTemplate = `{{define "book"}}
+          <a href="{{.URL}}" class="book">
+            {{- with .Cover}}
+            <img class="book-cover" src="{{.Src}}"{{with .Srcset}} srcset="{{.}}" sizes="(max-width: 600px) 45vw, 200px"{{end}} width="{{.Width}}" height="{{.Height}}" alt="" loading="lazy">
+            {{- end}}
+            <div class="book-info">
+              <div class="book-title">{{.Title}}</div>
+              <div class="book-author">{{.Author}}</div>
+              {{- with .Stars}}
+              <div class="book-rating">{{.}}</div>
+              {{- end}}
+            </div>
+          </a>
+{{- end}}`
+
+// groupBooks splits the library into books being read, books finished in
+// each year (newest first) and abandoned books.
+func (g *Generator) groupBooks(items []*LibraryItem) (reading []bookEntry, years []bookYear, abandoned []bookEntry) {
+	type dated struct {
+		item *LibraryItem
+		date time.Time
+	}
+	var read []dated
+	for _, item := range items {
+		switch item.Status {
+		case statusReading:
+			reading = append(reading, g.bookEntryFor(item))
+		case statusAbandoned:
+			abandoned = append(abandoned, g.bookEntryFor(item))
+		default:
+			date, _ := readDate(item)
+			read = append(read, dated{item, date})
+		}
+	}
+
+	sort.SliceStable(read, func(i, j int) bool { return read[i].date.After(read[j].date) })
+	for _, book := range read {
+		year := "Undated"
+		if !book.date.IsZero() {
+			year = book.date.Format("2006")
+		}
+		if len(years) == 0 || years[len(years)-1].Year != year {
+			years = append(years, bookYear{Year: year})
+		}
+		years[len(years)-1].Books = append(years[len(years)-1].Books, g.bookEntryFor(book.item))
+	}
+	return reading, years, abandoned
+}
+
+func (g *Generator) generateLibraryIndex(items []*LibraryItem) error {
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
+  <meta name="description" content="{{.Meta.Description}}">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
+  <title>{{.Meta.Title}}</title>
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
+</head>
+<body>
+  <nav class="navbar">
+    <div class="container nav-content">
+      <a href="/" class="logo">J</a>
+      <div class="nav-links">
+        <a href="/index.html#notes">Writing</a>
+        <a href="/about.html">About</a>
+      </div>
+    </div>
+  </nav>
+  <div class="container">
+    <header class="post-heading">
+      <h1>Library</h1>
+      <p class="post-description">Books I've read &amp; notes on them. <a href="{{.StatsURL}}">Reading stats</a></p>
+    </header>
+    <main>
+      {{- if .Reading}}
+      <section class="library-group">
+        <h2 class="section-header">Currently reading</h2>
+        <div class="library-grid">{{range .Reading}}{{template "book" .}}{{end}}
+        </div>
+      </section>
+      {{- end}}
+      {{- range .Years}}
+      <section class="library-group">
+        <h2 class="section-header">{{.Year}}</h2>
+        <div class="library-grid">{{range .Books}}{{template "book" .}}{{end}}
+        </div>
+      </section>
+      {{- end}}
+      {{- if .Abandoned}}
+      <section class="library-group">
+        <h2 class="section-header">Abandoned</h2>
+        <div class="library-grid">{{range .Abandoned}}{{template "book" .}}{{end}}
+        </div>
+      </section>
+      {{- end}}
+      <div class="back-button-container">
+        <a href="/" class="back-button">Back to home</a>
+      </div>
+    </main>
+  </div>
+</body>
+</html>`
+
+	t, err := template.New("library-index").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(bookTemplate)
+	}
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	reading, years, abandoned := g.groupBooks(items)
+
+	meta, err := g.listMeta("Library", "Books I've read & notes on them.", libraryIndexURL, nil)
+	if err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":      meta,
+		"StatsURL":  g.readingURL(),
+		"Reading":   reading,
+		"Years":     years,
+		"Abandoned": abandoned,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	if err := g.writePage(g.outputPath(libraryIndexURL), buf.String()); err != nil {
+		return fmt.Errorf("failed to write library index: %w", err)
+	}
+	return nil
+}
+
+func (g *Generator) generateReadingStats(items []*LibraryItem) error {
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
+  <meta name="description" content="{{.Description}}">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
+  <title>{{.Meta.Title}}</title>
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
+</head>
+<body>
+  <nav class="navbar">
+    <div class="container nav-content">
+      <a href="/" class="logo">J</a>
+      <div class="nav-links">
+        <a href="/index.html#notes">Writing</a>
+        <a href="/about.html">About</a>
+      </div>
+    </div>
+  </nav>
+  <div class="container">
+    <header class="post-heading">
+      <h1>Reading Stats</h1>
+      <p class="post-description">{{.Description}}</p>
+    </header>
+    <main class="reading-stats">
+      <dl class="reading-totals">
+        <dt>Read</dt><dd>{{.Read}}</dd>
+        <dt>Reading</dt><dd>{{.Reading}}</dd>
+        <dt>Abandoned</dt><dd>{{.Abandoned}}</dd>
+        {{- with .AverageRating}}
+        <dt>Average rating</dt><dd>{{.}}</dd>
+        {{- end}}
+      </dl>
+      {{- if .Years}}
+      <h2>Books per year</h2>
+      <table class="reading-years">
+        <thead>
+          <tr><th>Year</th><th>Books</th><th></th><th>Average rating</th></tr>
+        </thead>
+        <tbody>
+          {{- range .Years}}
+          <tr>
+            <td>{{.Year}}</td>
+            <td>{{.Books}}</td>
+            <td><meter min="0" max="{{$.MostInYear}}" value="{{.Books}}">{{.Books}}</meter></td>
+            <td>{{.AverageRating}}</td>
+          </tr>
+          {{- end}}
+        </tbody>
+      </table>
+      {{- end}}
+      {{- if .Tags}}
+      <h2>Top tags</h2>
+      <ol class="reading-tags">
+        {{- range .Tags}}
+        <li>{{.Name}} <span>{{.Count}}</span></li>
+        {{- end}}
+      </ol>
+      {{- end}}
+      <div class="back-button-container">
+        <a href="{{.LibraryURL}}" class="back-button">Back to library</a>
+      </div>
+    </main>
+  </div>
+</body>
+</html>`
+
+	t, err := template.New("reading").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	type yearStats struct {
+		Year          string
+		Books         int
+		AverageRating string
+		ratings       []float64
+	}
+	type tagCount struct {
+		Name  string
+		Count int
+	}
+
+	var read, reading, abandoned int
+	var ratings []float64
+	byYear := make(map[string]*yearStats)
+	tagCounts := make(map[string]int)
+	for _, item := range items {
+		switch item.Status {
+		case statusReading:
+			reading++
+			continue
+		case statusAbandoned:
+			abandoned++
+			continue
+		}
+		read++
+
+		year := "Undated"
+		if date, ok := readDate(item); ok {
+			year = date.Format("2006")
+		}
+		stats, ok := byYear[year]
+		if !ok {
+			stats = &yearStats{Year: year}
+			byYear[year] = stats
+		}
+		stats.Books++
+		if rating, ok := itemRating(item); ok {
+			stats.ratings = append(stats.ratings, rating)
+			ratings = append(ratings, rating)
+		}
+		for _, tag := range splitTags(item.Tags) {
+			tagCounts[strings.ToLower(tag)]++
+		}
+	}
+
+	var years []*yearStats
+	mostInYear := 0
+	for _, stats := range byYear {
+		stats.AverageRating = averageRating(stats.ratings)
+		if stats.Books > mostInYear {
+			mostInYear = stats.Books
+		}
+		years = append(years, stats)
+	}
+	// Newest first, with undated books last
+	sort.Slice(years, func(i, j int) bool {
+		if years[i].Year == "Undated" || years[j].Year == "Undated" {
+			return years[j].Year == "Undated" && years[i].Year != "Undated"
+		}
+		return years[i].Year > years[j].Year
+	})
+
+	var tags []tagCount
+	for name, count := range tagCounts {
+		tags = append(tags, tagCount{name, count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	if len(tags) > topTagCount {
+		tags = tags[:topTagCount]
+	}
+
+	books := "books"
+	if read == 1 {
+		books = "book"
+	}
+
+	description := fmt.Sprintf("%d %s read and counting.", read, books)
+	meta, err := g.listMeta("Reading Stats", description, g.readingURL(), nil)
+	if err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":          meta,
+		"Description":   description,
+		"LibraryURL":    libraryIndexURL,
+		"Read":          read,
+		"Reading":       reading,
+		"Abandoned":     abandoned,
+		"AverageRating": averageRating(ratings),
+		"Years":         years,
+		"MostInYear":    mostInYear,
+		"Tags":          tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	if err := g.writePage(g.outputPath(g.readingURL()), buf.String()); err != nil {
+		return fmt.Errorf("failed to write reading stats: %w", err)
+	}
+	return nil
+}
+
+// averageRating formats the mean of some ratings, or "" if there are none.
+func averageRating(ratings []float64) string {
+	if len(ratings) == 0 {
+		return ""
+	}
+	total := 0.0
+	for _, rating := range ratings {
+		total += rating
+	}
+	return fmt.Sprintf("%.1f", total/float64(len(ratings)))
+}
@@ -0,0 +1,343 @@
+package site
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const slugHistoryFile = "slug-history.json"
+
+// redirect sends visitors from a URL a page used to have to its current one.
+type redirect struct {
+	From   string
+	To     string
+	Source string
+}
+
+// oldPostURLs are the URLs a post had under a slug, both as a .html page
+// and under the current permalink pattern.
+func (g *Generator) oldPostURLs(post *Post, slug string) []string {
+	return uniqueStrings([]string{
+		permalink(legacyPostPattern, g.postVars(post, slug)),
+		permalink(g.config.Permalinks.Posts, g.postVars(post, slug)),
+	})
+}
+
+// aliasURLs turns an aliases entry into site paths. Bare slugs are treated
+// as old post slugs.
+func (g *Generator) aliasURLs(post *Post, alias string) []string {
+	if !strings.Contains(alias, "/") && !strings.Contains(alias, ".") {
+		return g.oldPostURLs(post, alias)
+	}
+	return []string{"/" + strings.TrimPrefix(alias, "/")}
+}
+
+// SetRebuildHistory makes the next build look through the whole git history
+// for renamed posts and add them to slug-history.json.
+func (g *Generator) SetRebuildHistory(enabled bool) {
+	g.rebuildHistory = enabled
+}
+
+// slugHistory reads the earlier slugs of each post from slug-history.json,
+// keyed by current slug. The file is the record of renames. Every build adds
+// the renames that are staged or in the last commit; the rest of git's
+// history is only searched when it's being rebuilt, since following every
+// post's log is slow and shallow clones don't have it.
+func (g *Generator) slugHistory(posts []*Post) (map[string][]string, bool, error) {
+	history := make(map[string][]string)
+	historyPath := filepath.Join(g.rootDir, slugHistoryFile)
+	original, err := os.ReadFile(historyPath)
+	if err == nil {
+		if err := json.Unmarshal(original, &history); err != nil {
+			return nil, false, fmt.Errorf("failed to parse %s: %w", slugHistoryFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, false, fmt.Errorf("failed to read %s: %w", slugHistoryFile, err)
+	}
+
+	current := make(map[string]bool, len(posts))
+	for _, post := range posts {
+		current[post.Slug] = true
+		if g.rebuildHistory {
+			history[post.Slug] = append(history[post.Slug], g.gitSlugs(post)...)
+		}
+	}
+	for slug, previous := range g.gitRecentRenames() {
+		history[slug] = append(history[slug], previous...)
+	}
+
+	// A post renamed again takes over the history of its previous slug
+	for slug, previous := range history {
+		if current[slug] {
+			continue
+		}
+		for _, post := range posts {
+			if containsString(history[post.Slug], slug) {
+				history[post.Slug] = append(history[post.Slug], previous...)
+				delete(history, slug)
+				break
+			}
+		}
+	}
+
+	for slug, previous := range history {
+		var cleaned []string
+		for _, old := range uniqueStrings(previous) {
+			if old != slug {
+				cleaned = append(cleaned, old)
+			}
+		}
+		sort.Strings(cleaned)
+		if len(cleaned) == 0 {
+			delete(history, slug)
+		} else {
+			history[slug] = cleaned
+		}
+	}
+
+	updated, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return nil, false, err
+	}
+	changed := !bytes.Equal(bytes.TrimSpace(original), updated) && (len(history) > 0 || len(original) > 0)
+	return history, changed, nil
+}
+
+func (g *Generator) saveSlugHistory(history map[string][]string) error {
+	content, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	historyPath := filepath.Join(g.rootDir, slugHistoryFile)
+	if err := os.WriteFile(historyPath, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", slugHistoryFile, err)
+	}
+	fmt.Printf("Slug history updated in %s\n", slugHistoryFile)
+	return nil
+}
+
+// gitSlugs returns the slugs a post had under earlier filenames, or nothing
+// when git isn't available.
+func (g *Generator) gitSlugs(post *Post) []string {
+	cmd := exec.Command("git", "log", "--follow", "--format=", "--name-only", "--", "posts/"+post.Filename)
+	cmd.Dir = g.rootDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var slugs []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if slug, ok := postSlugFromPath(strings.TrimSpace(line)); ok && slug != post.Slug {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// gitRecentRenames returns the earlier slugs of posts renamed in the staged
+// changes or the last commit, keyed by new slug, or nothing when git isn't
+// available.
+func (g *Generator) gitRecentRenames() map[string][]string {
+	renames := make(map[string][]string)
+	for _, args := range [][]string{
+		{"diff", "--cached", "-M", "--name-status", "--diff-filter=R", "--", "posts"},
+		{"diff", "-M", "--name-status", "--diff-filter=R", "HEAD~1", "HEAD", "--", "posts"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = g.rootDir
+		output, err := cmd.Output()
+		if err != nil {
+			continue
+		}
+
+		// Renames are listed as "R<similarity>\t<old path>\t<new path>"
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) != 3 {
+				continue
+			}
+			old, okOld := postSlugFromPath(fields[1])
+			slug, okNew := postSlugFromPath(fields[2])
+			if okOld && okNew && old != slug {
+				renames[slug] = append(renames[slug], old)
+			}
+		}
+	}
+	return renames
+}
+
+// postSlugFromPath returns the slug of a posts/<slug>.md path.
+func postSlugFromPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, "posts/") || !strings.HasSuffix(path, ".md") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(path, "posts/"), ".md"), true
+}
+
+// collectRedirects lists the old URLs of every page: the .html URLs pages
+// had before permalinks changed, and each post's slug history and aliases.
+func (g *Generator) collectRedirects(posts []*Post, items []*LibraryItem, history map[string][]string) []redirect {
+	var redirects []redirect
+	add := func(from []string, to, source string) {
+		for _, url := range from {
+			if url != to {
+				redirects = append(redirects, redirect{From: url, To: to, Source: source})
+			}
+		}
+	}
+
+	for _, post := range posts {
+		source := "posts/" + post.Filename
+		to := g.postURL(post)
+		add(g.oldPostURLs(post, post.Slug), to, source)
+		for _, slug := range history[post.Slug] {
+			add(g.oldPostURLs(post, slug), to, source)
+		}
+		for _, alias := range post.Aliases {
+			add(g.aliasURLs(post, alias), to, source)
+		}
+	}
+	for _, item := range items {
+		if strings.HasSuffix(item.Filename, ".md") {
+			add([]string{permalink(legacyLibraryPattern, map[string]string{"slug": item.ID})}, g.libraryURL(item), "library/"+item.Filename)
+		}
+	}
+	for _, series := range g.collectSeries(posts) {
+		add([]string{permalink(legacySeriesPattern, map[string]string{"slug": series.Slug})}, g.seriesURL(series), "series "+series.Title)
+	}
+	if len(linkPosts(posts)) > 0 {
+		add([]string{legacyLinksPattern}, g.linksURL(), "links stream")
+	}
+
+	sort.SliceStable(redirects, func(i, j int) bool { return redirects[i].From < redirects[j].From })
+	return redirects
+}
+
+// pageURLs returns the URLs of the pages the site generates or serves as-is.
+func (g *Generator) pageURLs(posts []*Post, items []*LibraryItem) map[string]string {
+	pages := map[string]string{
+		"/":           "index.html",
+		"/index.html": "index.html",
+		"/about.html": "about.html",
+	}
+	for _, post := range posts {
+		pages[g.postURL(post)] = "posts/" + post.Filename
+	}
+	for _, item := range items {
+		pages[g.libraryURL(item)] = "library/" + item.Filename
+	}
+	pages[libraryIndexURL] = "library index"
+	pages[g.readingURL()] = "reading stats"
+	for _, series := range g.collectSeries(posts) {
+		pages[g.seriesURL(series)] = "series " + series.Title
+	}
+	if len(linkPosts(posts)) > 0 {
+		pages[g.linksURL()] = "links stream"
+	}
+	return pages
+}
+
+// checkRedirects reports redirects that would replace a real page, that two
+// posts both claim or that would be written outside the site.
+func checkRedirects(redirects []redirect, pages map[string]string) []string {
+	var problems []string
+	claimed := make(map[string]redirect)
+	for _, r := range redirects {
+		if slices.Contains(strings.Split(r.From, "/"), "..") {
+			problems = append(problems, fmt.Sprintf("%s: old URL %s can't contain ..", r.Source, r.From))
+			continue
+		}
+		if page, ok := pages[r.From]; ok {
+			problems = append(problems, fmt.Sprintf("%s: old URL %s collides with %s", r.Source, r.From, page))
+			continue
+		}
+		if other, ok := claimed[r.From]; ok && other.To != r.To {
+			problems = append(problems, fmt.Sprintf("%s: old URL %s is also claimed by %s", r.Source, r.From, other.Source))
+			continue
+		}
+		claimed[r.From] = r
+	}
+	return problems
+}
+
+func (g *Generator) lintRedirects(posts []*Post) []string {
+	items, err := g.loadLibraryItems()
+	if err != nil {
+		return []string{fmt.Sprintf("failed to read library: %v", err)}
+	}
+	history, _, err := g.slugHistory(posts)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return checkRedirects(g.collectRedirects(posts, items, history), g.pageURLs(posts, items))
+}
+
+// generateRedirects writes a meta refresh page at each old URL and lists
+// them all in _redirects for hosts that support it.
+func (g *Generator) generateRedirects(posts []*Post, items []*LibraryItem) error {
+	history, changed, err := g.slugHistory(posts)
+	if err != nil {
+		return err
+	}
+	if changed {
+		if err := g.saveSlugHistory(history); err != nil {
+			return err
+		}
+	}
+
+	redirects := g.collectRedirects(posts, items, history)
+	if problems := checkRedirects(redirects, g.pageURLs(posts, items)); len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+
+	tmpl, err := template.New("redirect").Parse(redirectTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var lines strings.Builder
+	lines.WriteString("# Generated by the site builder. Do not edit.\n")
+	for _, r := range redirects {
+		fmt.Fprintf(&lines, "%s %s 301\n", r.From, r.To)
+
+		var stub strings.Builder
+		if err := tmpl.Execute(&stub, map[string]string{"To": r.To, "Canonical": g.absURL(r.To)}); err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+		if err := g.writePage(g.outputPath(r.From), stub.String()); err != nil {
+			return fmt.Errorf("failed to write redirect %s: %w", r.From, err)
+		}
+	}
+
+	redirectsPath := filepath.Join(g.rootDir, "_redirects")
+	if err := os.WriteFile(redirectsPath, []byte(lines.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write _redirects: %w", err)
+	}
+
+	fmt.Printf("Generated %d redirects\n", len(redirects))
+	return nil
+}
+
+const redirectTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Redirecting…</title>
+  <meta name="robots" content="noindex">
+  <link rel="canonical" href="{{.Canonical}}">
+  <meta http-equiv="refresh" content="0; url={{.To}}">
+</head>
+<body>
+  <p>This page has moved to <a href="{{.To}}">{{.Canonical}}</a>.</p>
+</body>
+</html>
+`
@@ -0,0 +1,58 @@
+package site
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitRecentRenames(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	g := NewGenerator()
+	g.rootDir = t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = g.rootDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(g.rootDir, "posts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"first.md", "second.md", "third.md"} {
+		content := "---\ntitle: " + name + "\n---\n\nThe body of " + name + ", long enough to be matched as a rename.\n"
+		if err := os.WriteFile(filepath.Join(g.rootDir, "posts", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "Add posts")
+	git("mv", "posts/first.md", "posts/one.md")
+	git("commit", "-q", "-m", "Rename first")
+	git("mv", "posts/second.md", "posts/two.md")
+
+	want := map[string][]string{"one": {"first"}, "two": {"second"}}
+	if got := g.gitRecentRenames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("gitRecentRenames = %v, want %v", got, want)
+	}
+}
+
+func TestCheckRedirectsRejectsParentPaths(t *testing.T) {
+	redirects := []redirect{
+		{From: "/posts/old.html", To: "/posts/new.html", Source: "posts/new.md"},
+		{From: "/../outside.html", To: "/posts/new.html", Source: "posts/new.md"},
+	}
+	problems := checkRedirects(redirects, map[string]string{})
+	if len(problems) != 1 || !strings.Contains(problems[0], "/../outside.html can't contain ..") {
+		t.Errorf("checkRedirects = %v, want the .. alias reported", problems)
+	}
+}
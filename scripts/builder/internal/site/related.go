@@ -0,0 +1,183 @@
+package site
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	relatedLimit = 3
+	// tagWeight is how much a shared tag counts relative to a perfect content match
+	tagWeight = 0.5
+)
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "had": true, "her": true,
+	"was": true, "one": true, "our": true, "out": true, "has": true, "have": true,
+	"his": true, "how": true, "its": true, "who": true, "did": true, "yes": true,
+	"she": true, "him": true, "they": true, "them": true, "this": true, "that": true,
+	"with": true, "from": true, "your": true, "what": true, "when": true, "which": true,
+	"will": true, "would": true, "there": true, "their": true, "about": true, "been": true,
+	"were": true, "into": true, "than": true, "then": true, "just": true, "also": true,
+	"some": true, "more": true, "does": true, "being": true,
+}
+
+// postLink is the minimal view of a post used for navigation blocks.
+type postLink struct {
+	Title       string
+	Description string
+	URL         string
+}
+
+func (g *Generator) linkTo(post *Post) *postLink {
+	if post == nil {
+		return nil
+	}
+	return &postLink{Title: post.Title, Description: post.Description, URL: g.postURL(post)}
+}
+
+// sortPostsByDate orders posts oldest first, using the slug to break ties.
+func sortPostsByDate(posts []*Post) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		a, _ := parseDate(posts[i].Created)
+		b, _ := parseDate(posts[j].Created)
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return posts[i].Slug < posts[j].Slug
+	})
+}
+
+// linkPosts sets the previous/next links within each section and the related
+// posts of every post.
+func (g *Generator) linkPosts(posts []*Post) {
+	sections := make(map[string][]*Post)
+	for _, post := range posts {
+		sections[post.Section] = append(sections[post.Section], post)
+	}
+	for _, sectionPosts := range sections {
+		sortPostsByDate(sectionPosts)
+		for i, post := range sectionPosts {
+			post.Previous, post.Next = nil, nil
+			if i > 0 {
+				post.Previous = sectionPosts[i-1]
+			}
+			if i < len(sectionPosts)-1 {
+				post.Next = sectionPosts[i+1]
+			}
+		}
+	}
+
+	vectors := g.tfidfVectors(posts)
+	for i, post := range posts {
+		type candidate struct {
+			post  *Post
+			score float64
+		}
+		var candidates []candidate
+		for j, other := range posts {
+			if i == j {
+				continue
+			}
+			score := float64(sharedTags(post, other))*tagWeight + cosineSimilarity(vectors[i], vectors[j])
+			if score > 0 {
+				candidates = append(candidates, candidate{other, score})
+			}
+		}
+		sort.SliceStable(candidates, func(a, b int) bool {
+			if candidates[a].score != candidates[b].score {
+				return candidates[a].score > candidates[b].score
+			}
+			return candidates[a].post.Slug < candidates[b].post.Slug
+		})
+
+		post.Related = nil
+		for k := 0; k < len(candidates) && k < relatedLimit; k++ {
+			post.Related = append(post.Related, candidates[k].post)
+		}
+	}
+}
+
+func sharedTags(a, b *Post) int {
+	tags := make(map[string]bool)
+	for _, tag := range splitTags(a.Tags) {
+		tags[strings.ToLower(tag)] = true
+	}
+	count := 0
+	for _, tag := range splitTags(b.Tags) {
+		if tags[strings.ToLower(tag)] {
+			count++
+		}
+	}
+	return count
+}
+
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var tokens []string
+	for _, word := range words {
+		if len([]rune(word)) > 2 && !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// tfidfVectors builds a normalised TF-IDF vector for each post's content.
+func (g *Generator) tfidfVectors(posts []*Post) []map[string]float64 {
+	counts := make([]map[string]float64, len(posts))
+	documentFrequency := make(map[string]int)
+
+	for i, post := range posts {
+		doc := g.newParser().Parse([]byte(post.Content))
+		counts[i] = make(map[string]float64)
+		for _, token := range tokenize(plainText(doc)) {
+			counts[i][token]++
+		}
+		for token := range counts[i] {
+			documentFrequency[token]++
+		}
+	}
+
+	vectors := make([]map[string]float64, len(posts))
+	for i, termCounts := range counts {
+		total := 0.0
+		for _, count := range termCounts {
+			total += count
+		}
+
+		vector := make(map[string]float64)
+		norm := 0.0
+		for token, count := range termCounts {
+			idf := math.Log(float64(len(posts)) / float64(documentFrequency[token]))
+			weight := count / total * idf
+			if weight > 0 {
+				vector[token] = weight
+				norm += weight * weight
+			}
+		}
+		norm = math.Sqrt(norm)
+		for token := range vector {
+			vector[token] /= norm
+		}
+		vectors[i] = vector
+	}
+
+	return vectors
+}
+
+func cosineSimilarity(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	dot := 0.0
+	for token, weight := range a {
+		dot += weight * b[token]
+	}
+	return dot
+}
@@ -0,0 +1,256 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// pageMeta is the social and search metadata rendered into the <head> of a page.
+type pageMeta struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string
+	Type        string
+	Published   string
+	Modified    string
+	Tags        []string
+	SiteName    string
+	Locale      string
+	Twitter     string
+	JSONLD      template.JS
+	Feeds       []feedLink
+}
+
+// metaTemplate is shared by every generated page and expects a pageMeta as "Meta".
+const metaTemplate = `{{define "meta"}}<link rel="canonical" href="{{.URL}}">
+  <meta property="og:site_name" content="{{.SiteName}}">
+  <meta property="og:locale" content="{{.Locale}}">
+  <meta property="og:title" content="{{.Title}}">
+  <meta property="og:description" content="{{.Description}}">
+  <meta property="og:type" content="{{.Type}}">
+  <meta property="og:url" content="{{.URL}}">
+  <meta property="og:image" content="{{.Image}}">
+  {{- if .Published}}
+  <meta property="article:published_time" content="{{.Published}}">
+  {{- end}}
+  {{- if .Modified}}
+  <meta property="article:modified_time" content="{{.Modified}}">
+  {{- end}}
+  {{- range .Tags}}
+  <meta property="article:tag" content="{{.}}">
+  {{- end}}
+  <meta name="twitter:card" content="summary_large_image">
+  {{- if .Twitter}}
+  <meta name="twitter:site" content="{{.Twitter}}">
+  {{- end}}
+  <meta name="twitter:title" content="{{.Title}}">
+  <meta name="twitter:description" content="{{.Description}}">
+  <meta name="twitter:image" content="{{.Image}}">
+  <script type="application/ld+json">{{.JSONLD}}</script>
+  {{- range .Feeds}}
+  <link rel="alternate" type="{{.Type}}" title="{{.Title}}" href="{{.URL}}">
+  {{- end}}{{end}}`
+
+// absURL resolves a site-relative path or absolute URL against the configured base URL.
+func (g *Generator) absURL(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return g.config.BaseURL + "/" + strings.TrimPrefix(path, "/")
+}
+
+// siteLanguage is the locale as a language tag, such as en-GB.
+func (g *Generator) siteLanguage() string {
+	if g.config.Locale == "" {
+		return "en"
+	}
+	return strings.ReplaceAll(g.config.Locale, "_", "-")
+}
+
+// isoDate converts a frontmatter date to ISO 8601, or returns "" if it can't be parsed.
+func isoDate(value string) string {
+	date, ok := parseDate(value)
+	if !ok {
+		return ""
+	}
+	return date.Format(time.RFC3339)
+}
+
+func splitTags(tags string) []string {
+	var result []string
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func (g *Generator) pageImage(cover string) string {
+	if cover == "" {
+		cover = g.config.DefaultImage
+	}
+	return g.absURL(cover)
+}
+
+func (g *Generator) postMeta(post *Post) (pageMeta, error) {
+	// Posts without a cover use their generated preview image
+	cover := post.Cover
+	if cover == "" {
+		cover = g.previewPath(post)
+	}
+
+	meta := pageMeta{
+		Title:       post.Title + " - " + g.config.Title,
+		Description: post.Description,
+		URL:         g.absURL(g.postURL(post)),
+		Image:       g.pageImage(cover),
+		Type:        "article",
+		Published:   isoDate(post.Created),
+		Modified:    isoDate(post.Updated),
+		Tags:        splitTags(post.Tags),
+		SiteName:    g.config.Title,
+		Locale:      g.config.Locale,
+		Twitter:     g.config.Twitter,
+		Feeds:       g.pageFeeds(post.Section, splitTags(post.Tags)),
+	}
+
+	data := map[string]interface{}{
+		"@context":         "https://schema.org",
+		"@type":            "BlogPosting",
+		"headline":         post.Title,
+		"description":      post.Description,
+		"url":              meta.URL,
+		"mainEntityOfPage": meta.URL,
+		"image":            meta.Image,
+		"author":           g.personLD(),
+		"publisher":        g.personLD(),
+	}
+	if meta.Published != "" {
+		data["datePublished"] = meta.Published
+	}
+	if meta.Modified != "" {
+		data["dateModified"] = meta.Modified
+	}
+	if len(meta.Tags) > 0 {
+		data["keywords"] = strings.Join(meta.Tags, ", ")
+	}
+
+	jsonLD, err := marshalJSONLD(data)
+	if err != nil {
+		return pageMeta{}, fmt.Errorf("failed to build structured data for post %s: %w", post.Slug, err)
+	}
+	meta.JSONLD = jsonLD
+	return meta, nil
+}
+
+func (g *Generator) libraryMeta(item *LibraryItem) (pageMeta, error) {
+	// Placeholder covers are SVG, which social previews don't show
+	cover := item.Cover
+	if generated := g.covers[item.ID]; generated != nil && generated.Image != "" {
+		cover = generated.Image
+	}
+
+	meta := pageMeta{
+		Title:       item.Title + " - " + g.config.Title,
+		Description: item.Description,
+		URL:         g.absURL(g.libraryURL(item)),
+		Image:       g.pageImage(cover),
+		Type:        "article",
+		Published:   isoDate(item.Created),
+		Modified:    isoDate(item.Updated),
+		Tags:        splitTags(item.Tags),
+		SiteName:    g.config.Title,
+		Locale:      g.config.Locale,
+		Twitter:     g.config.Twitter,
+	}
+
+	book := map[string]interface{}{
+		"@type": "Book",
+		"name":  item.Title,
+		"image": meta.Image,
+	}
+	if item.Author != "" {
+		book["author"] = map[string]string{"@type": "Person", "name": item.Author}
+	}
+	if item.Year != "" && item.Year != "undefined" {
+		book["datePublished"] = item.Year
+	}
+
+	data := map[string]interface{}{
+		"@context":     "https://schema.org",
+		"@type":        "Review",
+		"name":         item.Title,
+		"description":  item.Description,
+		"url":          meta.URL,
+		"itemReviewed": book,
+		"author":       g.personLD(),
+	}
+	if meta.Published != "" {
+		data["datePublished"] = meta.Published
+	}
+	if meta.Modified != "" {
+		data["dateModified"] = meta.Modified
+	}
+
+	jsonLD, err := marshalJSONLD(data)
+	if err != nil {
+		return pageMeta{}, fmt.Errorf("failed to build structured data for library item %s: %w", item.ID, err)
+	}
+	meta.JSONLD = jsonLD
+	return meta, nil
+}
+
+// listMeta is the metadata of a generated page that lists other pages, such
+// as a series.
+func (g *Generator) listMeta(title, description, url string, feeds []feedLink) (pageMeta, error) {
+	meta := pageMeta{
+		Title:       title + " - " + g.config.Title,
+		Description: description,
+		URL:         g.absURL(url),
+		Image:       g.pageImage(""),
+		Type:        "website",
+		SiteName:    g.config.Title,
+		Locale:      g.config.Locale,
+		Twitter:     g.config.Twitter,
+		Feeds:       feeds,
+	}
+
+	data := map[string]interface{}{
+		"@context":    "https://schema.org",
+		"@type":       "CollectionPage",
+		"name":        title,
+		"description": description,
+		"url":         meta.URL,
+		"author":      g.personLD(),
+	}
+
+	jsonLD, err := marshalJSONLD(data)
+	if err != nil {
+		return pageMeta{}, fmt.Errorf("failed to build structured data for %s: %w", url, err)
+	}
+	meta.JSONLD = jsonLD
+	return meta, nil
+}
+
+func (g *Generator) personLD() map[string]string {
+	return map[string]string{
+		"@type": "Person",
+		"name":  g.config.Author,
+		"url":   g.absURL("/"),
+	}
+}
+
+func marshalJSONLD(data map[string]interface{}) (template.JS, error) {
+	// json.Marshal escapes <, > and & so the output is safe inside a <script> element
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(encoded), nil
+}
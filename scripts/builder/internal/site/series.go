@@ -0,0 +1,249 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+)
+
+// Series groups the posts that share a series frontmatter field.
+type Series struct {
+	Title string
+	Slug  string
+	Posts []*Post
+}
+
+// seriesBox is the "Part N of M" block rendered on each member post.
+type seriesBox struct {
+	Title string
+	URL   string
+	Part  int
+	Total int
+	Parts []seriesPart
+}
+
+type seriesPart struct {
+	Part    int
+	Title   string
+	URL     string
+	Current bool
+}
+
+// collectSeries groups posts by series, ordered by series_order, and links
+// each post back to its series.
+func (g *Generator) collectSeries(posts []*Post) []*Series {
+	bySlug := make(map[string]*Series)
+	var all []*Series
+
+	for _, post := range posts {
+		if post.Series == "" {
+			continue
+		}
+		slug := g.slugify(post.Series)
+		series, ok := bySlug[slug]
+		if !ok {
+			series = &Series{Title: post.Series, Slug: slug}
+			bySlug[slug] = series
+			all = append(all, series)
+		}
+		series.Posts = append(series.Posts, post)
+		post.SeriesInfo = series
+	}
+
+	for _, series := range all {
+		sort.SliceStable(series.Posts, func(i, j int) bool {
+			return series.Posts[i].SeriesOrder < series.Posts[j].SeriesOrder
+		})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Slug < all[j].Slug })
+
+	return all
+}
+
+func (g *Generator) seriesBoxFor(post *Post) *seriesBox {
+	series := post.SeriesInfo
+	if series == nil {
+		return nil
+	}
+
+	box := &seriesBox{
+		Title: series.Title,
+		URL:   g.seriesURL(series),
+		Total: len(series.Posts),
+	}
+	for i, member := range series.Posts {
+		part := seriesPartNumber(member, i)
+		if member == post {
+			box.Part = part
+		}
+		// A gap in series_order still counts the missing parts
+		box.Total = max(box.Total, part)
+		box.Parts = append(box.Parts, seriesPart{
+			Part:    part,
+			Title:   member.Title,
+			URL:     g.postURL(member),
+			Current: member == post,
+		})
+	}
+	return box
+}
+
+// seriesPartNumber is the part number of the i'th post of a series: its
+// series_order, or its position when it has none.
+func seriesPartNumber(post *Post, i int) int {
+	if post.SeriesOrder > 0 {
+		return post.SeriesOrder
+	}
+	return i + 1
+}
+
+// lintSeries reports duplicate, missing and non-contiguous series_order values.
+func (g *Generator) lintSeries(posts []*Post) []string {
+	var problems []string
+
+	for _, series := range g.collectSeries(posts) {
+		orders := make(map[int]*Post)
+		for _, post := range series.Posts {
+			if post.SeriesOrder < 1 {
+				problems = append(problems, fmt.Sprintf("posts/%s: series %q needs a positive series_order", post.Filename, series.Title))
+				continue
+			}
+			if other, ok := orders[post.SeriesOrder]; ok {
+				problems = append(problems, fmt.Sprintf("posts/%s: series %q has duplicate series_order %d (also posts/%s)",
+					post.Filename, series.Title, post.SeriesOrder, other.Filename))
+				continue
+			}
+			orders[post.SeriesOrder] = post
+		}
+
+		for part := 1; part <= len(series.Posts); part++ {
+			if _, ok := orders[part]; !ok && len(orders) > 0 {
+				problems = append(problems, fmt.Sprintf("series %q is missing part %d", series.Title, part))
+			}
+		}
+	}
+
+	return problems
+}
+
+func (g *Generator) generateSeriesPages(allSeries []*Series) error {
+	if len(allSeries) == 0 {
+		return nil
+	}
+
+	for _, series := range allSeries {
+		htmlContent, err := g.generateSeriesHTML(series)
+		if err != nil {
+			return fmt.Errorf("failed to generate HTML for series %s: %w", series.Slug, err)
+		}
+
+		htmlPath := g.outputPath(g.seriesURL(series))
+		if err := g.writePage(htmlPath, htmlContent); err != nil {
+			return fmt.Errorf("failed to write HTML file for series %s: %w", series.Slug, err)
+		}
+	}
+
+	fmt.Printf("Generated %d series pages\n", len(allSeries))
+	return nil
+}
+
+func (g *Generator) generateSeriesHTML(series *Series) (string, error) {
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0, viewport-fit=cover">
+  <meta name="description" content="{{.Description}}">
+  {{template "meta" .Meta}}
+  <link rel="apple-touch-icon" sizes="180x180" href="/{{asset "images/apple-touch-icon.png"}}">
+  <link rel="icon" type="image/png" sizes="32x32" href="/{{asset "images/favicon-32x32.png"}}">
+  <link rel="icon" type="image/png" sizes="16x16" href="/{{asset "images/favicon-16x16.png"}}">
+  <link rel="manifest" href="/{{asset "site.webmanifest"}}">
+  <title>{{.Meta.Title}}</title>
+  <link rel="stylesheet" href="/{{asset "fonts.css"}}">
+  <link rel="stylesheet" href="/{{asset "styles.css"}}">
+</head>
+<body>
+  <nav class="navbar">
+    <div class="container nav-content">
+      <a href="/" class="logo">J</a>
+      <div class="nav-links">
+        <a href="/index.html#notes">Writing</a>
+        <a href="/about.html">About</a>
+      </div>
+    </div>
+  </nav>
+  <div class="container">
+    <header class="post-heading">
+      <h1>{{.Title}}</h1>
+      <p class="post-description">{{.Description}}</p>
+    </header>
+    <main>
+      <div class="notes-list">
+        {{- range .Posts}}
+        <a href="{{.URL}}" class="note-row">
+          <div class="note-header">
+            <time>Part {{.Part}}</time>
+            <h3>{{.Title}}</h3>
+          </div>
+          <p>{{.Description}}</p>
+        </a>
+        {{- end}}
+      </div>
+      <div class="back-button-container">
+        <a href="/" class="back-button">Back to home</a>
+      </div>
+    </main>
+  </div>
+</body>
+</html>`
+
+	t, err := template.New("series").Funcs(g.assetFuncs()).Parse(tmpl)
+	if err == nil {
+		_, err = t.Parse(metaTemplate)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	type seriesEntry struct {
+		Part        int
+		Title       string
+		Description string
+		URL         string
+	}
+	var entries []seriesEntry
+	for i, post := range series.Posts {
+		entries = append(entries, seriesEntry{
+			Part:        seriesPartNumber(post, i),
+			Title:       post.Title,
+			Description: post.Description,
+			URL:         g.postURL(post),
+		})
+	}
+
+	parts := "parts"
+	if len(series.Posts) == 1 {
+		parts = "part"
+	}
+	description := fmt.Sprintf("A series in %d %s.", len(series.Posts), parts)
+
+	meta, err := g.listMeta(series.Title, description, g.seriesURL(series), g.pageFeeds("", nil))
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	err = t.Execute(&buf, map[string]interface{}{
+		"Meta":        meta,
+		"Title":       series.Title,
+		"Description": description,
+		"Posts":       entries,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
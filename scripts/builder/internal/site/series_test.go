@@ -0,0 +1,24 @@
+package site
+
+import "testing"
+
+func TestSeriesBoxUsesSeriesOrder(t *testing.T) {
+	g := NewGenerator()
+	var posts []*Post
+	for _, order := range []int{4, 1, 2} {
+		posts = append(posts, &Post{Title: "Part", Slug: "part", Series: "Gaps", SeriesOrder: order})
+	}
+	g.collectSeries(posts)
+
+	box := g.seriesBoxFor(posts[0])
+	if box.Part != 4 || box.Total != 4 {
+		t.Errorf("box is part %d of %d, want part 4 of 4", box.Part, box.Total)
+	}
+	var parts []int
+	for _, part := range box.Parts {
+		parts = append(parts, part.Part)
+	}
+	if len(parts) != 3 || parts[0] != 1 || parts[1] != 2 || parts[2] != 4 {
+		t.Errorf("parts = %v, want [1 2 4]", parts)
+	}
+}
@@ -0,0 +1,322 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shortcodePattern matches {{< name key="value" >}} and {{< /name >}}.
+var shortcodePattern = regexp.MustCompile(`\{\{<\s*(/?)([A-Za-z0-9_-]+)((?:\s+[A-Za-z0-9_-]+=(?:"(?:[^"\\]|\\.)*"|'[^']*'|[^\s">']+))*)\s*>\}\}`)
+
+var shortcodeParamPattern = regexp.MustCompile(`([A-Za-z0-9_-]+)=(?:"((?:[^"\\]|\\.)*)"|'([^']*)'|([^\s">']+))`)
+
+// shortcodeData is passed to shortcode templates.
+type shortcodeData struct {
+	Params map[string]string
+	Inner  template.HTML
+	Book   *bookCard
+}
+
+type bookCard struct {
+	Title       string
+	Author      string
+	Description string
+	URL         string
+	Cover       *bookCover
+}
+
+// shortcodeSet holds the templates in templates/shortcodes and the library
+// items that bookcard shortcodes refer to.
+type shortcodeSet struct {
+	templates map[string]*template.Template
+	books     map[string]*LibraryItem
+}
+
+func (g *Generator) loadShortcodes() (*shortcodeSet, error) {
+	if g.shortcodes != nil {
+		return g.shortcodes, nil
+	}
+
+	set := &shortcodeSet{
+		templates: make(map[string]*template.Template),
+		books:     make(map[string]*LibraryItem),
+	}
+
+	funcs := template.FuncMap{
+		"required": func(name, value string) (string, error) {
+			if value == "" {
+				return "", fmt.Errorf("missing required parameter %q", name)
+			}
+			return value, nil
+		},
+	}
+
+	files, err := filepath.Glob(filepath.Join(g.rootDir, "templates", "shortcodes", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read shortcode template %s: %w", path, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".html")
+		t, err := template.New(name).Funcs(funcs).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse shortcode template %s: %w", path, err)
+		}
+		set.templates[name] = t
+	}
+
+	items, err := g.loadLibraryItems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library: %w", err)
+	}
+	for _, item := range items {
+		set.books[item.ID] = item
+	}
+
+	g.shortcodes = set
+	return set, nil
+}
+
+// shortcodePlaceholder stands in for a rendered shortcode while the markdown
+// is converted. Markdown leaves HTML comments alone, and one on its own line
+// is a block rather than a paragraph.
+const shortcodePlaceholder = "<!--shortcode:%d-->"
+
+// expandShortcodes renders the shortcodes in markdown and replaces each with
+// a placeholder, returning the rendered HTML for restoreShortcodes to put
+// back once the markdown is HTML. Paired shortcodes have their inner
+// markdown rendered first. Errors name the file and line of the offending
+// shortcode; bodyLine is the line the content starts on within the file.
+func (g *Generator) expandShortcodes(content, file string, bodyLine int) (string, []string, error) {
+	if !strings.Contains(content, "{{<") {
+		return content, nil, nil
+	}
+
+	set, err := g.loadShortcodes()
+	if err != nil {
+		return "", nil, err
+	}
+
+	fences := fencedRanges(content)
+	var out strings.Builder
+	var shortcodes []string
+	pos := 0
+
+	for {
+		loc := nextShortcode(content, pos, fences)
+		if loc == nil {
+			out.WriteString(content[pos:])
+			return out.String(), shortcodes, nil
+		}
+
+		line := bodyLine + strings.Count(content[:loc[0]], "\n")
+		match := content[loc[0]:loc[1]]
+		parts := shortcodePattern.FindStringSubmatch(match)
+		name := parts[2]
+		if parts[1] == "/" {
+			return "", nil, fmt.Errorf("%s:%d: closing shortcode {{< /%s >}} without an opening one", file, line, name)
+		}
+
+		t, ok := set.templates[name]
+		if !ok {
+			return "", nil, fmt.Errorf("%s:%d: unknown shortcode %q", file, line, name)
+		}
+
+		data := shortcodeData{Params: make(map[string]string)}
+		for _, param := range shortcodeParamPattern.FindAllStringSubmatch(parts[3], -1) {
+			data.Params[param[1]] = strings.ReplaceAll(param[2], `\"`, `"`) + param[3] + param[4]
+		}
+
+		end := loc[1]
+		if closeStart, closeEnd := findClosingShortcode(content, name, loc[1], fences); closeStart >= 0 {
+			innerLine := bodyLine + strings.Count(content[:loc[1]], "\n")
+			inner, innerShortcodes, err := g.expandShortcodes(content[loc[1]:closeStart], file, innerLine)
+			if err != nil {
+				return "", nil, err
+			}
+			innerHTML, err := g.markdownToHTML(inner)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s:%d: %w", file, line, err)
+			}
+			data.Inner = template.HTML(restoreShortcodes(innerHTML, innerShortcodes))
+			end = closeEnd
+		}
+
+		if name == "bookcard" {
+			item, ok := set.books[data.Params["id"]]
+			if !ok {
+				return "", nil, fmt.Errorf("%s:%d: bookcard refers to unknown library item %q", file, line, data.Params["id"])
+			}
+			cover, err := g.bookCoverFor(item)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s:%d: %w", file, line, err)
+			}
+			data.Book = &bookCard{
+				Title:       item.Title,
+				Author:      item.Author,
+				Description: item.Description,
+				URL:         g.libraryURL(item),
+				Cover:       cover,
+			}
+		}
+
+		var rendered strings.Builder
+		if err := t.Execute(&rendered, data); err != nil {
+			return "", nil, fmt.Errorf("%s:%d: shortcode %q: %w", file, line, name, err)
+		}
+
+		out.WriteString(content[pos:loc[0]])
+		fmt.Fprintf(&out, shortcodePlaceholder, len(shortcodes))
+		shortcodes = append(shortcodes, strings.TrimSpace(rendered.String()))
+		pos = end
+	}
+}
+
+// restoreShortcodes swaps the placeholders left by expandShortcodes for the
+// rendered shortcodes. A paragraph can't hold a block, so one written within
+// a paragraph ends it, and the text after it starts another.
+func restoreShortcodes(htmlContent string, shortcodes []string) string {
+	for i, rendered := range shortcodes {
+		placeholder := fmt.Sprintf(shortcodePlaceholder, i)
+		at := strings.Index(htmlContent, placeholder)
+		if at < 0 {
+			continue
+		}
+		before, after := htmlContent[:at], htmlContent[at+len(placeholder):]
+
+		if isBlockHTML(rendered) && inParagraph(before) {
+			before = strings.TrimRight(before, " \n")
+			after = strings.TrimLeft(after, " \n")
+			if trimmed, ok := strings.CutSuffix(before, "<p>"); ok {
+				before = trimmed
+			} else {
+				before += "</p>\n"
+			}
+			if trimmed, ok := strings.CutPrefix(after, "</p>"); ok {
+				after = trimmed
+			} else {
+				after = "\n<p>" + after
+			}
+		}
+		htmlContent = before + rendered + after
+	}
+	return htmlContent
+}
+
+// isBlockHTML reports whether HTML starts with a block element.
+func isBlockHTML(content string) bool {
+	tag, ok := strings.CutPrefix(strings.TrimSpace(content), "<")
+	if !ok {
+		return false
+	}
+	if end := strings.IndexAny(tag, " \t\n/>"); end >= 0 {
+		tag = tag[:end]
+	}
+	return blockElements[strings.ToLower(tag)]
+}
+
+// inParagraph reports whether the end of rendered HTML is inside a <p>.
+func inParagraph(content string) bool {
+	return strings.LastIndex(content, "<p>") > strings.LastIndex(content, "</p>")
+}
+
+// stripShortcodes removes the shortcode tags from markdown, keeping the inner
+// markdown of paired shortcodes, for reading its text without rendering it.
+func stripShortcodes(content string) string {
+	if !strings.Contains(content, "{{<") {
+		return content
+	}
+
+	fences := fencedRanges(content)
+	var out strings.Builder
+	pos := 0
+	for {
+		loc := nextShortcode(content, pos, fences)
+		if loc == nil {
+			out.WriteString(content[pos:])
+			return out.String()
+		}
+		out.WriteString(content[pos:loc[0]])
+		pos = loc[1]
+	}
+}
+
+// nextShortcode finds the next shortcode at or after pos outside fenced code.
+func nextShortcode(content string, pos int, fences [][2]int) []int {
+	for pos < len(content) {
+		loc := shortcodePattern.FindStringIndex(content[pos:])
+		if loc == nil {
+			return nil
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if !inRanges(start, fences) {
+			return []int{start, end}
+		}
+		pos = end
+	}
+	return nil
+}
+
+// findClosingShortcode returns the position of the {{< /name >}} that closes
+// a shortcode opened just before from, allowing for nesting.
+func findClosingShortcode(content, name string, from int, fences [][2]int) (int, int) {
+	depth := 0
+	pos := from
+	for {
+		loc := nextShortcode(content, pos, fences)
+		if loc == nil {
+			return -1, -1
+		}
+		parts := shortcodePattern.FindStringSubmatch(content[loc[0]:loc[1]])
+		if parts[2] == name {
+			if parts[1] == "/" {
+				if depth == 0 {
+					return loc[0], loc[1]
+				}
+				depth--
+			} else {
+				depth++
+			}
+		}
+		pos = loc[1]
+	}
+}
+
+// fencedRanges returns the byte ranges of fenced code blocks.
+func fencedRanges(content string) [][2]int {
+	var ranges [][2]int
+	offset := 0
+	start := -1
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if start < 0 {
+				start = offset
+			} else {
+				ranges = append(ranges, [2]int{start, offset + len(line)})
+				start = -1
+			}
+		}
+		offset += len(line)
+	}
+	if start >= 0 {
+		ranges = append(ranges, [2]int{start, len(content)})
+	}
+	return ranges
+}
+
+func inRanges(pos int, ranges [][2]int) bool {
+	for _, r := range ranges {
+		if pos >= r[0] && pos < r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// shortcodeSite is a site with the repository's shortcode templates and one
+// library item, for bookcards to refer to.
+var shortcodeSite = filepath.Join("testdata", "site")
+
+// shortcodeCovers stands in for the generated covers, so rendering doesn't
+// write any into testdata.
+var shortcodeCovers = map[string]*bookCover{
+	"poor-charlies-almanack": {
+		Src:    "/images/books/covers/poor-charlies-almanack.0a1b2c-200.jpg",
+		Srcset: "/images/books/covers/poor-charlies-almanack.0a1b2c-200.jpg 200w, /images/books/covers/poor-charlies-almanack.0a1b2c-400.jpg 400w",
+		Width:  200,
+		Height: 300,
+	},
+}
+
+func renderShortcodes(g *Generator, content string) (string, error) {
+	expanded, shortcodes, err := g.expandShortcodes(content, "posts/test.md", 1)
+	if err != nil {
+		return "", err
+	}
+	htmlContent, err := g.markdownToHTML(expanded)
+	if err != nil {
+		return "", err
+	}
+	return restoreShortcodes(htmlContent, shortcodes), nil
+}
+
+// TestShortcodeGolden renders each testdata/shortcodes/<name>.md with the
+// shortcodes of testdata/site and compares it with <name>.html.
+func TestShortcodeGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "shortcodes", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden inputs in testdata/shortcodes")
+	}
+
+	for _, input := range inputs {
+		base := strings.TrimSuffix(input, ".md")
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			g := NewGenerator()
+			g.rootDir = shortcodeSite
+			g.covers = shortcodeCovers
+
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := renderShortcodes(g, string(source))
+			if err != nil {
+				t.Fatalf("failed to render %s: %v", input, err)
+			}
+
+			golden := base + ".html"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s (run go test -update to create it): %v", golden, err)
+			}
+			if got != string(want) {
+				t.Errorf("%s doesn't match %s\ngot:\n%s\nwant:\n%s", input, golden, got, want)
+			}
+		})
+	}
+}
+
+func TestShortcodeErrors(t *testing.T) {
+	tests := []struct {
+		name, content, at, want string
+	}{
+		{
+			name:    "missing required parameter",
+			content: "Intro\n\n{{< figure caption=\"A\" >}}\n",
+			at:      "posts/test.md:3:",
+			want:    `missing required parameter "src"`,
+		},
+		{
+			name:    "unknown shortcode",
+			content: "{{< chart >}}\n",
+			at:      "posts/test.md:1:",
+			want:    `unknown shortcode "chart"`,
+		},
+		{
+			name:    "closing without opening",
+			content: "Text\n{{< /details >}}\n",
+			at:      "posts/test.md:2:",
+			want:    "closing shortcode {{< /details >}} without an opening one",
+		},
+		{
+			name:    "unknown library item",
+			content: "{{< bookcard id=\"missing\" >}}\n",
+			at:      "posts/test.md:1:",
+			want:    `bookcard refers to unknown library item "missing"`,
+		},
+	}
+
+	for _, test := range tests {
+		g := NewGenerator()
+		g.rootDir = shortcodeSite
+		_, err := renderShortcodes(g, test.content)
+		if err == nil || !strings.HasPrefix(err.Error(), test.at) || !strings.HasSuffix(err.Error(), test.want) {
+			t.Errorf("%s: error = %v, want %s ... %s", test.name, err, test.at, test.want)
+		}
+	}
+}
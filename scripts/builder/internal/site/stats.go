@@ -0,0 +1,94 @@
+package site
+
+import (
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+const (
+	wordsPerMinute = 200
+	moreMarker     = "<!--more-->"
+)
+
+// computePostStats fills in the derived word count, reading time and excerpt
+// of a post from its markdown AST. Shortcode tags are left out of the text
+// but what's inside paired ones is kept.
+func (g *Generator) computePostStats(post *Post) {
+	content := stripShortcodes(post.Content)
+	doc := g.newParser().Parse([]byte(content))
+
+	post.WordCount = len(strings.Fields(plainText(doc)))
+	post.ReadingTime = (post.WordCount + wordsPerMinute - 1) / wordsPerMinute
+	if post.ReadingTime < 1 {
+		post.ReadingTime = 1
+	}
+
+	// Text before a <!--more--> marker wins over the first paragraph
+	if before, _, found := strings.Cut(content, moreMarker); found {
+		summary := g.newParser().Parse([]byte(before))
+		post.Excerpt = strings.Join(strings.Fields(summaryText(summary)), " ")
+	} else {
+		post.Excerpt = strings.Join(strings.Fields(firstParagraph(doc)), " ")
+	}
+}
+
+// plainText collects the readable text of a document, skipping code blocks
+// and raw HTML.
+func plainText(doc ast.Node) string {
+	var text strings.Builder
+	ast.WalkFunc(doc, func(node ast.Node, entering bool) ast.WalkStatus {
+		switch n := node.(type) {
+		case *ast.CodeBlock, *ast.HTMLBlock, *ast.HTMLSpan:
+			return ast.SkipChildren
+		case *ast.Text:
+			if entering {
+				text.Write(n.Literal)
+			}
+		case *ast.Code:
+			if entering {
+				text.Write(n.Literal)
+			}
+		case *ast.Math:
+			if entering {
+				text.Write(n.Literal)
+			}
+		case *displayMath:
+			if entering {
+				text.Write(n.Literal)
+			}
+		case *ast.Paragraph, *ast.Heading, *ast.ListItem, *ast.TableCell:
+			if !entering {
+				text.WriteString(" ")
+			}
+		case *ast.Softbreak, *ast.Hardbreak:
+			text.WriteString(" ")
+		}
+		return ast.GoToNext
+	})
+	return text.String()
+}
+
+// summaryText is the text of a document without its headings, so the title
+// repeated at the top of a post doesn't end up in the excerpt.
+func summaryText(doc ast.Node) string {
+	var text strings.Builder
+	for _, child := range doc.GetChildren() {
+		if _, ok := child.(*ast.Heading); ok {
+			continue
+		}
+		text.WriteString(plainText(child))
+	}
+	return text.String()
+}
+
+func firstParagraph(doc ast.Node) string {
+	for _, child := range doc.GetChildren() {
+		if paragraph, ok := child.(*ast.Paragraph); ok {
+			if text := plainText(paragraph); strings.TrimSpace(text) != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
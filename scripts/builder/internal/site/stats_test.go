@@ -0,0 +1,32 @@
+package site
+
+import "testing"
+
+func TestExcerptAfterLinkResolution(t *testing.T) {
+	g := NewGenerator()
+	post := &Post{
+		Title:    "Squares",
+		Slug:     "squares",
+		Filename: "squares.md",
+		Content:  "# Squares\n\nAs [[aphorisms]] and [[aphorisms|the list]] say, $x^2$ grows {{< details summary=\"More\" >}}quickly{{< /details >}}.\n",
+	}
+	other := &Post{Title: "Aphorisms", Description: "Sayings", Slug: "aphorisms", Filename: "aphorisms.md"}
+
+	if err := g.resolveLinks([]*Post{post, other}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "As Aphorisms and the list say, x^2 grows quickly."
+	if post.Excerpt != want {
+		t.Errorf("excerpt = %q, want %q", post.Excerpt, want)
+	}
+	if post.Description != want {
+		t.Errorf("description = %q, want the excerpt", post.Description)
+	}
+	if other.Backlinks[0].Description != want {
+		t.Errorf("backlink description = %q, want the excerpt", other.Backlinks[0].Description)
+	}
+	if other.Description != "Sayings" {
+		t.Errorf("description from frontmatter was replaced with %q", other.Description)
+	}
+}
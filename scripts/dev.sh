@@ -8,12 +8,13 @@ case "$1" in
         ./scripts/build.sh
         ;;
     "new")
-        if [ -z "$2" ]; then
-            echo "❌ Usage: ./scripts/dev.sh new \"Post Title\""
-            exit 1
+        # Anything not given on the command line is asked for, including the type
+        args=(-cmd new-post -title "$2" -interactive -open)
+        if [ -n "$3" ]; then
+            args+=(-type "$3")
         fi
-        echo "📝 Creating new post: $2"
-        ./scripts/builder/bin/site -cmd new-post -title "$2" -desc "Description for $2" -tags "tag1,tag2" -section "Notes"
+        echo "📝 Creating new ${3:-post}: $2"
+        ./scripts/builder/bin/site "${args[@]}"
         ;;
     "serve")
         echo "🌐 Starting local server on http://localhost:8000"
@@ -42,7 +43,7 @@ case "$1" in
         echo ""
         echo "Commands:"
         echo "  build              Build the site"
-        echo "  new \"Title\"        Create a new post"
+        echo "  new \"Title\" [type] Create a new post (note, essay, book, link)"
         echo "  serve              Start local server"
         echo "  watch              Watch for changes and rebuild"
         echo "  clean              Clean up generated files"
@@ -0,0 +1,17 @@
+#!/bin/bash
+
+# Downloads the site's fonts into fonts/ from their OFL releases in the
+# Google Fonts repository. The variable fonts cover every weight the design
+# uses, and the build subsets them, so only the files themselves are needed.
+
+set -e
+
+FONTS_URL="https://raw.githubusercontent.com/google/fonts/main/ofl"
+
+mkdir -p fonts
+
+echo "🔤 Downloading fonts..."
+curl -fL -o fonts/Fraunces.ttf "${FONTS_URL}/fraunces/Fraunces%5BSOFT,WONK,opsz,wght%5D.ttf"
+curl -fL -o fonts/Inter.ttf "${FONTS_URL}/inter/Inter%5Bopsz,wght%5D.ttf"
+
+echo "✅ Fonts saved to fonts/, commit them with the next build"
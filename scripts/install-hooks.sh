@@ -12,7 +12,13 @@ echo "🔄 Pre-commit: Rebuilding site..."
 
 # Stage all generated files
 echo "📝 Staging generated files..."
-git add index.html sitemap.xml
+# Patterns are matched by git, so files the build removed are staged too
+for path in index.html sitemap.xml fonts.css fonts/subset reading.html links.html _headers _redirects \
+    feed.xml feed.json 'posts/*.html' 'posts/*.png' 'library/*.html' images/books/covers \
+    sections tags page archive '[0-9][0-9][0-9][0-9]/*' api \
+    'styles.*.css' 'fonts.*.css' 'site.*.webmanifest' 'images/apple-touch-icon.*.png' 'images/favicon-*.*.png'; do
+    git add -A -- "$path" 2>/dev/null
+done
 
 echo "✅ Pre-commit hook completed"
 EOL
@@ -26,7 +32,13 @@ echo "🔄 Pre-push: Rebuilding site..."
 
 # Stage generated files
 echo "📝 Staging generated files..."
-git add index.html sitemap.xml
+# Patterns are matched by git, so files the build removed are staged too
+for path in index.html sitemap.xml fonts.css fonts/subset reading.html links.html _headers _redirects \
+    feed.xml feed.json 'posts/*.html' 'posts/*.png' 'library/*.html' images/books/covers \
+    sections tags page archive '[0-9][0-9][0-9][0-9]/*' api \
+    'styles.*.css' 'fonts.*.css' 'site.*.webmanifest' 'images/apple-touch-icon.*.png' 'images/favicon-*.*.png'; do
+    git add -A -- "$path" 2>/dev/null
+done
 
 # Check if there are any changes to commit
 if [[ -n $(git status --porcelain) ]]; then